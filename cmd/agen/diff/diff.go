@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Kegian/agen/internal/flags"
+	"github.com/Kegian/agen/openapi/apidiff"
+	"github.com/Kegian/agen/openapi/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var flagFormat = flags.FormatText
+
+func init() {
+	DiffCmd.Flags().VarP(&flagFormat, "format", "f", `Format of the report, allowed: "text", "json"`)
+}
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff <old.yml> <new.yml>",
+	Short: "Compare two versions of api file and detect breaking changes",
+	Long: "Compare two versions of api file and detect breaking changes.\n" +
+		"Exit code is 1 if there are breaking changes and 2 if files can't be parsed.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		oldDoc, ok := parseFile(args[0])
+		newDoc, newOk := parseFile(args[1])
+		if !ok || !newOk {
+			os.Exit(2)
+		}
+
+		report := apidiff.Compare(oldDoc, newDoc)
+		if err := printReport(report); err != nil {
+			return err
+		}
+		if report.HasBreaking() {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+// parseFile prints errors of the file, warnings are skipped as they don't affect the comparison
+func parseFile(path string) (parser.Document, bool) {
+	doc, diags := parser.DiagnoseFile(path)
+	if !diags.HasErrors() {
+		return doc, true
+	}
+	for i := range diags {
+		if diags[i].Severity == parser.SeverityError {
+			fmt.Fprintln(os.Stderr, diags[i].Error())
+		}
+	}
+	return doc, false
+}
+
+type jsonReport struct {
+	Breaking    int            `json:"breaking"`
+	NonBreaking int            `json:"non_breaking"`
+	Changes     apidiff.Report `json:"changes"`
+}
+
+func printReport(report apidiff.Report) error {
+	breaking, nonBreaking := report.Breaking(), report.NonBreaking()
+
+	if flagFormat == flags.FormatJSON {
+		if report == nil {
+			report = apidiff.Report{}
+		}
+		data, err := json.MarshalIndent(jsonReport{
+			Breaking:    len(breaking),
+			NonBreaking: len(nonBreaking),
+			Changes:     report,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(report) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+	for _, group := range []struct {
+		title   string
+		changes apidiff.Report
+	}{
+		{"Breaking changes", breaking},
+		{"Non-breaking changes", nonBreaking},
+	} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d):\n", group.title, len(group.changes))
+		for i := range group.changes {
+			fmt.Printf("  %s [%s]\n", group.changes[i].String(), group.changes[i].Code)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d breaking, %d non-breaking changes\n", len(breaking), len(nonBreaking))
+	return nil
+}
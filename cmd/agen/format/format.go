@@ -0,0 +1,59 @@
+package format
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Kegian/agen/internal/diff"
+	"github.com/Kegian/agen/openapi/formatter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	check       bool
+	sortSchemas bool
+)
+
+func init() {
+	FormatCmd.Flags().BoolVar(&check, "check", false, `Check that files are formatted, diff is printed otherwise`)
+	FormatCmd.Flags().BoolVar(&sortSchemas, "sort-schemas", false, `Sort schemas by name`)
+}
+
+var FormatCmd = &cobra.Command{
+	Use:   "fmt <api.yml>...",
+	Short: "Format api files",
+	Long: "Format api files in canonical layout keeping comments, files are rewritten in place.\n" +
+		"With --check files are not changed, exit code is 1 if some of them are not formatted.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		unformatted := false
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			res, err := formatter.Format(data, formatter.Options{SortSchemas: sortSchemas})
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if string(res) == string(data) {
+				continue
+			}
+
+			if check {
+				unformatted = true
+				fmt.Print(diff.Unified(path, path, string(data), string(res)))
+				continue
+			}
+			if err := os.WriteFile(path, res, 0644); err != nil {
+				return err
+			}
+			fmt.Println(path)
+		}
+		if unformatted {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
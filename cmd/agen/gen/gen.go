@@ -1,13 +1,19 @@
 package gen
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/Kegian/agen/internal/diff"
+	"github.com/Kegian/agen/internal/flags"
 	"github.com/Kegian/agen/openapi/gen"
 	"github.com/Kegian/agen/openapi/parser"
 
@@ -16,9 +22,13 @@ import (
 
 var (
 	flagGenType = genTypeAll
+	flagFormat  = flags.FormatText
+	specVersion = specVersionFlag(gen.Version30)
+	specFormat  = specFormatFlag(gen.FormatYAML)
 	inputPath   string
 	outputPath  string
 	verbose     bool
+	check       bool
 )
 
 func init() {
@@ -26,7 +36,11 @@ func init() {
 	GenCmd.Flags().StringVarP(&inputPath, "input", "i", "", `Path to input api file, example: "api.yml"`)
 	_ = GenCmd.MarkFlagRequired("input")
 	GenCmd.Flags().StringVarP(&outputPath, "output", "o", "", `Path to output folder, example: "internal/generated/"`)
+	GenCmd.Flags().VarP(&flagFormat, "format", "f", `Format of diagnostics, allowed: "text", "json"`)
+	GenCmd.Flags().Var(&specVersion, "spec-version", `Version of OpenAPI specification, allowed: "3.0", "3.1"`)
+	GenCmd.Flags().Var(&specFormat, "spec-format", `Format of OpenAPI specification, allowed: "yaml", "json"`)
 	GenCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, `Verbose print parsed schema`)
+	GenCmd.Flags().BoolVar(&check, "check", false, `Check that generated files in output folder are up to date, diff is printed otherwise`)
 }
 
 var GenCmd = &cobra.Command{
@@ -44,76 +58,224 @@ var GenCmd = &cobra.Command{
 			}
 		}
 
-		data, err := os.ReadFile(inputPath)
-		if err != nil {
+		document, diags := parser.DiagnoseFile(inputPath)
+		if flagGenType != genTypeOAPI {
+			diags = append(diags, parser.CheckOgen(&document)...)
+		}
+		if err := printDiagnostics(diags); err != nil {
 			return err
 		}
-
-		document, err := parser.ParseDocument(data)
-		if err != nil {
-			fmt.Println(err)
+		if diags.HasErrors() {
 			os.Exit(1)
 		}
+		// Only diagnostics are printed to stdout in json format
+		if flagFormat == flags.FormatJSON && outputPath == "" {
+			return nil
+		}
 		if verbose {
 			parser.PrettyPrint(document)
 		}
-		spec, err := gen.GenerateSpec(document)
+		spec, err := gen.GenerateSpecWithOptions(document, gen.Options{
+			Version: gen.Version(specVersion),
+			Format:  gen.Format(specFormat),
+			Ogen:    flagGenType != genTypeOAPI,
+		})
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		switch {
-		case outputPath == "":
+		if check {
+			return checkFiles(document, spec)
+		}
+
+		if outputPath == "" {
 			fmt.Println(spec)
-		case flagGenType == genTypeOAPI:
-			err := os.WriteFile(outputPath, []byte(spec), 0644)
-			if err != nil {
-				return err
-			}
-		default:
-			swaggerPath := filepath.Join(outputPath, "server")
-			err := os.MkdirAll(swaggerPath, os.ModePerm)
-			if err != nil {
-				return err
-			}
-			specPath := filepath.Join(swaggerPath, "openapi.yml")
-			err = os.WriteFile(specPath, []byte(spec), 0644)
-			if err != nil {
-				return err
-			}
-			swaggerGenPath := filepath.Join(swaggerPath, "swagger_gen.go")
-			err = os.WriteFile(swaggerGenPath, []byte(swaggerGen), 0644)
+			return nil
+		}
+
+		for _, f := range specFiles(document, spec) {
+			err := os.MkdirAll(filepath.Dir(f.Path), os.ModePerm)
 			if err != nil {
 				return err
 			}
-			serverGenPath := filepath.Join(swaggerPath, "server_gen.go")
-			serverGenFinal := strings.ReplaceAll(serverGen, "${URL}", document.Settings.URL)
-			err = os.WriteFile(serverGenPath, []byte(serverGenFinal), 0644)
+			err = os.WriteFile(f.Path, []byte(f.Data), 0644)
 			if err != nil {
 				return err
 			}
+		}
+		if flagGenType == genTypeOAPI {
+			return nil
+		}
 
-			ogenPath := filepath.Join(outputPath, "oapi")
+		specPath := filepath.Join(outputPath, "server", specFileName())
+		return runOgen(specPath, filepath.Join(outputPath, "oapi"), os.Stdout)
+	},
+}
 
-			ogen := exec.Command(
-				"ogen",
-				"--target", ogenPath,
-				"--package", "oapi",
-				"--clean",
-				specPath,
-			)
-			ogen.Stdout = os.Stdout
-			ogen.Stderr = os.Stdout
+type outputFile struct {
+	Path string
+	Data string
+}
 
-			err = ogen.Run()
+// specFiles returns spec and server files written to the output path
+func specFiles(document parser.Document, spec string) []outputFile {
+	if flagGenType == genTypeOAPI {
+		return []outputFile{{Path: outputPath, Data: spec}}
+	}
+	swaggerPath := filepath.Join(outputPath, "server")
+	return []outputFile{
+		{Path: filepath.Join(swaggerPath, specFileName()), Data: spec},
+		{Path: filepath.Join(swaggerPath, "swagger_gen.go"), Data: strings.ReplaceAll(swaggerGen, "${SPEC}", specFileName())},
+		{Path: filepath.Join(swaggerPath, "server_gen.go"), Data: strings.ReplaceAll(serverGen, "${URL}", document.Settings.URL)},
+	}
+}
+
+// specFileName returns name of the spec file in the server folder
+func specFileName() string {
+	if specFormat == specFormatFlag(gen.FormatJSON) {
+		return "openapi.json"
+	}
+	return "openapi.yml"
+}
+
+func runOgen(specPath, ogenPath string, out io.Writer) error {
+	ogen := exec.Command(
+		"ogen",
+		"--target", ogenPath,
+		"--package", "oapi",
+		"--clean",
+		specPath,
+	)
+	ogen.Stdout = out
+	ogen.Stderr = out
+	return ogen.Run()
+}
+
+// checkFiles regenerates files in memory (ogen files in a temporary folder)
+// and exits with the unified diff if the files in the output path are stale
+func checkFiles(document parser.Document, spec string) error {
+	if outputPath == "" {
+		return errors.New("flag 'output' should be specified for checking generated files")
+	}
+
+	diffs := []string{}
+	for _, f := range specFiles(document, spec) {
+		d, err := diffFile(f.Path, f.Data)
+		if err != nil {
+			return err
+		}
+		diffs = append(diffs, d)
+	}
+
+	if flagGenType != genTypeOAPI {
+		tmp, err := os.MkdirTemp("", "agen")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+
+		specPath := filepath.Join(tmp, specFileName())
+		err = os.WriteFile(specPath, []byte(spec), 0644)
+		if err != nil {
+			return err
+		}
+		err = runOgen(specPath, filepath.Join(tmp, "oapi"), os.Stderr)
+		if err != nil {
+			return err
+		}
+		d, err := diffDirs(filepath.Join(outputPath, "oapi"), filepath.Join(tmp, "oapi"))
+		if err != nil {
+			return err
+		}
+		diffs = append(diffs, d...)
+	}
+
+	res := strings.Join(diffs, "")
+	if res != "" {
+		fmt.Print(res)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// diffFile returns diff of the file with generated data, missing file is treated as empty
+func diffFile(path string, data string) (string, error) {
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return diff.Unified(path, path, string(old), data), nil
+}
+
+// diffDirs returns diffs of all files of the output folder with the files of the generated folder
+func diffDirs(dir, generated string) ([]string, error) {
+	files := map[string]struct{}{}
+	for _, root := range []string{dir, generated} {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return nil
+				}
 				return err
 			}
+			if !d.IsDir() {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				files[rel] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	diffs := []string{}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(generated, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		d, err := diffFile(filepath.Join(dir, name), string(data))
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
 
+// printDiagnostics prints diagnostics in the selected format, in text format errors are printed to stdout
+// and warnings to stderr to keep generated spec clean
+func printDiagnostics(diags parser.Diagnostics) error {
+	if flagFormat == flags.FormatJSON {
+		if diags == nil {
+			diags = parser.Diagnostics{}
+		}
+		data, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
 		return nil
-	},
+	}
+	for i := range diags {
+		if diags[i].Severity == parser.SeverityError {
+			fmt.Println(diags[i].Error())
+		} else {
+			fmt.Fprintln(os.Stderr, string(diags[i].Severity)+": "+diags[i].Error())
+		}
+	}
+	return nil
 }
 
 type genType string
@@ -145,6 +307,46 @@ func (e *genType) Type() string {
 	return "genType"
 }
 
+type specVersionFlag gen.Version
+
+func (v *specVersionFlag) String() string {
+	return string(*v)
+}
+
+func (v *specVersionFlag) Set(val string) error {
+	switch gen.Version(val) {
+	case gen.Version30, gen.Version31:
+		*v = specVersionFlag(val)
+		return nil
+	default:
+		return errors.New(`must be one of "3.0" or "3.1"`)
+	}
+}
+
+func (v *specVersionFlag) Type() string {
+	return "specVersion"
+}
+
+type specFormatFlag gen.Format
+
+func (f *specFormatFlag) String() string {
+	return string(*f)
+}
+
+func (f *specFormatFlag) Set(val string) error {
+	switch gen.Format(val) {
+	case gen.FormatYAML, gen.FormatJSON:
+		*f = specFormatFlag(val)
+		return nil
+	default:
+		return errors.New(`must be one of "yaml" or "json"`)
+	}
+}
+
+func (f *specFormatFlag) Type() string {
+	return "specFormat"
+}
+
 var swaggerGen = `// Code generated by agen, DO NOT EDIT.
 
 package server
@@ -156,7 +358,7 @@ import (
 	"github.com/flowchartsman/swaggerui"
 )
 
-//go:embed openapi.yml
+//go:embed ${SPEC}
 var SwaggerSpec []byte
 
 func SwaggerHandler(prefix string) http.Handler {
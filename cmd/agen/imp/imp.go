@@ -0,0 +1,76 @@
+package imp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Kegian/agen/openapi/importer"
+	"github.com/Kegian/agen/openapi/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	inputPath  string
+	outputPath string
+	force      bool
+)
+
+func init() {
+	ImportCmd.Flags().StringVarP(&inputPath, "input", "i", "", `Path to OpenAPI 3 file, example: "openapi.yaml"`)
+	_ = ImportCmd.MarkFlagRequired("input")
+	ImportCmd.Flags().StringVarP(&outputPath, "output", "o", "", `Path to output api file, example: "api.yml", stdout is used if not specified`)
+	ImportCmd.Flags().BoolVar(&force, "force", false, `Overwrite existing output file`)
+}
+
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Convert OpenAPI 3 spec into agen api file",
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if outputPath != "" && !force {
+			if _, err := os.Stat(outputPath); err == nil {
+				return fmt.Errorf("file %s already exists, use --force to overwrite it", outputPath)
+			}
+		}
+
+		data, err := os.ReadFile(inputPath)
+		if err != nil {
+			return err
+		}
+
+		res, warnings, err := importer.Import(data)
+		if err != nil {
+			for _, d := range parser.ToDiagnostics(err) {
+				d.File = inputPath
+				fmt.Fprintln(os.Stderr, d.Error())
+			}
+			os.Exit(1)
+		}
+		for _, d := range warnings {
+			d.File = inputPath
+			fmt.Fprintf(os.Stderr, "%s: %s\n", d.Severity, d.Error())
+		}
+
+		text, err := importer.GenDSL(res)
+		if err != nil {
+			return err
+		}
+
+		// Output is checked to be a valid agen file, problems are reported but the file is kept for manual fixes
+		if _, err := parser.ParseDocument([]byte(text)); err != nil {
+			fmt.Fprintln(os.Stderr, "generated file has problems, fix them manually:")
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		if outputPath == "" {
+			fmt.Print(text)
+			return nil
+		}
+		if err := os.WriteFile(outputPath, []byte(text), 0644); err != nil {
+			return errors.New("failed to write output file: " + err.Error())
+		}
+		fmt.Fprintln(os.Stderr, "agen file generated:", outputPath)
+		return nil
+	},
+}
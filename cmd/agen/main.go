@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/Kegian/agen/cmd/agen/diff"
+	"github.com/Kegian/agen/cmd/agen/format"
 	"github.com/Kegian/agen/cmd/agen/gen"
+	"github.com/Kegian/agen/cmd/agen/imp"
 	in "github.com/Kegian/agen/cmd/agen/init"
 	"github.com/Kegian/agen/cmd/agen/update"
 	"github.com/Kegian/agen/cmd/agen/web"
@@ -21,6 +24,9 @@ func init() {
 	rootCmd.AddCommand(in.InitCmd)
 	rootCmd.AddCommand(gen.GenCmd)
 	rootCmd.AddCommand(web.WebCmd)
+	rootCmd.AddCommand(imp.ImportCmd)
+	rootCmd.AddCommand(diff.DiffCmd)
+	rootCmd.AddCommand(format.FormatCmd)
 	rootCmd.AddCommand(update.UpdateCmd)
 }
 
@@ -10,6 +10,7 @@ logsTab = document.getElementById('logs-tab');
 
 generateTab = document.getElementById('generate-tab');
 saveTab = document.getElementById('save-tab');
+formatTab = document.getElementById('format-tab');
 pathTab = document.getElementById('path-tab');
 
 editorOAPITab = document.getElementById('editor-oapi');
@@ -120,6 +121,26 @@ generateTab.onclick = function() {
         });
 };
 
+formatTab.onclick = function() {
+    fetch('/format', {
+        method: 'POST',
+        body: JSON.stringify({text:editor.getValue()}),
+    })
+        .then((response) => {
+            if (response.ok) {
+                return response.json();
+            }
+            return Promise.reject(response);
+        })
+        .then((json) => handleFormat(json))
+        .catch((response) => {
+            console.log(response.status, response.statusText);
+            response.text().then((text) => {
+                handleFormat({error: text})
+            })
+        });
+};
+
 saveTab.onclick = function() {
     var doSave = confirm('Перезаписать файл ' + pathTab.innerHTML + ' ?')
     if (!doSave) {
@@ -158,6 +179,22 @@ function handleGeneration(data) {
     }
 }
 
+function handleFormat(data) {
+    if (data.error.length !== 0) {
+        showLogs(true)
+        logsTab.innerHTML = 'Error: ' + data.error
+        return
+    }
+    if (data.text === editor.getValue()) {
+        return
+    }
+    // Cursor is kept on the same line, undo restores the text before formatting
+    var cursor = editor.getCursorPosition();
+    editor.session.doc.setValue(data.text);
+    editor.clearSelection()
+    editor.moveCursorToPosition(cursor);
+}
+
 function showGenerate(flag) {
     if (flag) {
         generateTab.style.background = 'linear-gradient(0deg, rgba(68,126,66,1) 13%, rgba(104,224,96,1) 100%)';
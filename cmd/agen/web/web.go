@@ -12,6 +12,7 @@ import (
 
 	"github.com/Kegian/agen/cmd/agen/web/static"
 	"github.com/Kegian/agen/internal/markdown"
+	"github.com/Kegian/agen/openapi/formatter"
 	"github.com/Kegian/agen/openapi/gen"
 	"github.com/Kegian/agen/openapi/parser"
 
@@ -51,6 +52,7 @@ var WebCmd = &cobra.Command{
 		r.Handle("/file", http.HandlerFunc(ArgFileHandler))
 		r.Handle("/save", http.HandlerFunc(SaveHandler))
 		r.Handle("/generate", http.HandlerFunc(GenerateHandler))
+		r.Handle("/format", http.HandlerFunc(FormatHandler))
 		r.PathPrefix("/").Handler(http.FileServer(http.FS(static.Static)))
 
 		fmt.Println("Web server started at http://" + addr + "/")
@@ -138,7 +140,7 @@ func GenerateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	document, err := parser.ParseDocument([]byte(req.Text))
+	document, err := parser.ParseDocumentFile(initialPath, []byte(req.Text))
 	if err != nil {
 		DoResponse(w, &GenerateRes{Error: err.Error()})
 		return
@@ -164,6 +166,32 @@ func GenerateHandler(w http.ResponseWriter, r *http.Request) {
 	DoResponse(w, &GenerateRes{OpenAPI: spec, SwaggerID: swaggerID, YouTrack: youtrack})
 }
 
+type FormatReq struct {
+	Text string `json:"text"`
+}
+
+type FormatRes struct {
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+func FormatHandler(w http.ResponseWriter, r *http.Request) {
+	var req FormatReq
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	text, err := formatter.Format([]byte(req.Text), formatter.Options{})
+	if err != nil {
+		DoResponse(w, &FormatRes{Error: err.Error()})
+		return
+	}
+
+	DoResponse(w, &FormatRes{Text: string(text)})
+}
+
 func DoResponse[T any](w http.ResponseWriter, res *T) {
 	data, err := json.Marshal(res)
 	if err != nil {
@@ -223,9 +251,11 @@ settings:
   url: /api/v1
   title: User service
   version: 1.0.0
+  security_schemes:
+    bearerAuth: bearer
   security:
     - {}
-    - bearer: []
+    - bearerAuth: []
 
 api:
   _common:
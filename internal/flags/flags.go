@@ -0,0 +1,30 @@
+package flags
+
+import "errors"
+
+// OutputFormat is a format of command output: diagnostics, reports, etc.
+type OutputFormat string
+
+const (
+	FormatText OutputFormat = "text"
+	FormatJSON OutputFormat = "json"
+)
+
+func (f *OutputFormat) String() string {
+	return string(*f)
+}
+
+func (f *OutputFormat) Set(v string) error {
+	switch v {
+	case "text", "json":
+		*f = OutputFormat(v)
+		return nil
+	default:
+		return errors.New(`must be one of "text" or "json"`)
+	}
+}
+
+// Type is only used in help text
+func (f *OutputFormat) Type() string {
+	return "outputFormat"
+}
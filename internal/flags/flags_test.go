@@ -0,0 +1,13 @@
+package flags
+
+import "testing"
+
+func TestOutputFormat(t *testing.T) {
+	f := FormatText
+	if err := f.Set("json"); err != nil || f != FormatJSON || f.String() != "json" {
+		t.Errorf("got %s (%v), want json", f, err)
+	}
+	if err := f.Set("xml"); err == nil || f != FormatJSON {
+		t.Errorf("got %s (%v), want error and unchanged value", f, err)
+	}
+}
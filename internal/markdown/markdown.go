@@ -3,6 +3,7 @@ package markdown
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/Kegian/agen/openapi/parser"
@@ -10,16 +11,18 @@ import (
 
 func GenMarkdown(spec parser.Document) (string, error) {
 	gen := &Generator{
-		spec: spec,
-		regs: map[string]*parser.Schema{},
+		spec:  spec,
+		regs:  map[string]*parser.Schema{},
+		stack: map[string]struct{}{},
 	}
 	return gen.Generate()
 }
 
 type Generator struct {
 	bytes.Buffer
-	spec parser.Document
-	regs map[string]*parser.Schema
+	spec  parser.Document
+	regs  map[string]*parser.Schema
+	stack map[string]struct{} // Custom types being generated, used to stop on recursive types
 }
 
 func (g *Generator) Add(strs ...string) {
@@ -47,14 +50,18 @@ func (g *Generator) Generate() (string, error) {
 
 func (g *Generator) GenerateMethod(m parser.Method) error {
 	g.Add(
-		`# <p style="text-align:center;"><span style="color:darkgreen;">**`,
+		`# <p style="text-align:center;"><span style="color:`, getMethodColor(m.Method), `;">**`,
 		m.Method, " ", m.Path,
 		`**</span></p>`,
 	)
 	g.Add()
 	g.Add(`| Метод | Endpoint | Описание |`)
 	g.Add(`| --- | --- | --- |`)
-	g.Add(`| `, m.Method, ` | `, m.Path, ` | `, m.Description, ` |`)
+	desc := getText(m.Description)
+	if m.Deprecated {
+		desc = strings.TrimSpace("**Устарел.** " + desc)
+	}
+	g.Add(`| `, m.Method, ` | `, m.Path, ` | `, desc, ` |`)
 	g.Add()
 	g.Add(`## **Request**`)
 	g.Add()
@@ -79,8 +86,38 @@ func (g *Generator) GenerateMethod(m parser.Method) error {
 		g.Add()
 	}
 
+	if len(m.Request.Headers) != 0 {
+		g.Add(`Параметры заголовков`)
+		g.Add()
+		if err := g.GenerateParams(m.Request.Headers); err != nil {
+			return err
+		}
+		g.Add()
+	}
+
+	if len(m.Request.Cookies) != 0 {
+		g.Add(`Параметры cookie`)
+		g.Add()
+		if err := g.GenerateParams(m.Request.Cookies); err != nil {
+			return err
+		}
+		g.Add()
+	}
+
 	if m.Request.Body != nil {
-		g.Add(`Параметры body`)
+		title := `Параметры body`
+		contentTypes := strings.Join(m.Request.Body.ContentTypes, ", ")
+		switch contentTypes {
+		case "":
+		case parser.ContentTypeForm, parser.ContentTypeMultipart:
+			title = `Параметры form (` + contentTypes + `)`
+		default:
+			title += ` (` + contentTypes + `)`
+		}
+		if m.Request.Body.Optional {
+			title += ` (необязательное)`
+		}
+		g.Add(title)
 		g.Add()
 		if err := g.GenerateBody(m.Request.Body); err != nil {
 			return err
@@ -93,7 +130,25 @@ func (g *Generator) GenerateMethod(m parser.Method) error {
 	g.Add(`### **Параметры ответа:**`)
 	g.Add()
 
+	if m.Response.Code != "" && m.Response.Code != "200" {
+		g.Add(`Код ответа: `, m.Response.Code)
+		g.Add()
+	}
+
+	if len(m.Response.Headers) != 0 {
+		g.Add(`Заголовки ответа`)
+		g.Add()
+		if err := g.GenerateParams(m.Response.Headers); err != nil {
+			return err
+		}
+		g.Add()
+	}
+
 	if m.Response.Body != nil {
+		if len(m.Response.Body.ContentTypes) != 0 {
+			g.Add(`Тип содержимого: `, strings.Join(m.Response.Body.ContentTypes, ", "))
+			g.Add()
+		}
 		if err := g.GenerateBody(m.Response.Body); err != nil {
 			return err
 		}
@@ -128,15 +183,52 @@ func (g *Generator) GenerateBody(body *parser.Schema) error {
 
 func (g *Generator) GenerateSchema(root string, isRef bool, desc string, s *parser.Schema) error {
 	switch {
+	case s.IsMap:
+		typ := s.Type.Name()
+		if !s.Type.IsRef() {
+			var err error
+			typ, err = getType(s.Type)
+			if err != nil {
+				return err
+			}
+		}
+		g.GenerateRow(root, isRef, desc, "map[string]"+typ, s)
+		return nil
+	case s.Type.IsUnion():
+		variants := make([]string, 0, len(s.Variants))
+		for _, v := range s.Variants {
+			variants = append(variants, v.Name())
+		}
+		typ := string(s.Type) + "(" + strings.Join(variants, ", ") + ")"
+		if s.Discriminator.Property != "" {
+			typ += " by " + s.Discriminator.Property
+		}
+		g.GenerateRow(root, isRef, desc, typ, s)
+		return nil
 	case s.Type.IsRef():
 		ref, ok := g.regs[s.Type.Name()]
 		if !ok {
 			return fmt.Errorf("unknown type `%s`", string(s.Type))
 		}
+		if _, ok := g.stack[s.Type.Name()]; ok {
+			g.GenerateRow(root, isRef, desc, s.Type.Name(), s)
+			return nil
+		}
 		if !isRef {
 			root += s.Name + "."
 		}
-		return g.GenerateSchema(root, true, s.Description, ref)
+		g.stack[s.Type.Name()] = struct{}{}
+		defer delete(g.stack, s.Type.Name())
+		if err := g.GenerateSchema(root, true, s.Description, ref); err != nil {
+			return err
+		}
+		// Fields added to the reference by the common body
+		for _, f := range s.Fields {
+			if err := g.GenerateSchema(root, false, "", &f); err != nil {
+				return err
+			}
+		}
+		return nil
 	case s.Type == parser.TypeObject:
 		if !isRef {
 			root += s.Name + "."
@@ -152,19 +244,40 @@ func (g *Generator) GenerateSchema(root string, isRef bool, desc string, s *pars
 		if err != nil {
 			return err
 		}
-		name := root
-		if !isRef {
-			name += s.Name
-		}
-		name = strings.Trim(name, ".")
-		if desc == "" {
-			desc = s.Description
-		}
-		g.Add(`| `, name, ` | `, typ, getArr(s.IsArray), ` | `, getReq(s.Optional), ` | `, desc, ` |`)
+		g.GenerateRow(root, isRef, desc, typ, s)
 	}
 	return nil
 }
 
+func (g *Generator) GenerateRow(root string, isRef bool, desc string, typ string, s *parser.Schema) {
+	name := root
+	if !isRef {
+		name += s.Name
+	}
+	name = strings.Trim(name, ".")
+	if desc == "" {
+		desc = s.Description
+	}
+	if s.Default != "" {
+		desc = strings.TrimSpace(desc + " (по умолчанию: `" + s.Default + "`)")
+	}
+	if len(s.Enum) != 0 {
+		typ += " (" + strings.Join(s.Enum, ", ") + ")"
+	}
+	typ += getArr(s.IsArray)
+	if c := getConstraints(s.Constraints); c != "" {
+		typ += " [" + c + "]"
+	}
+	req := getReq(s.Optional)
+	if s.Nullable {
+		req += ", nullable"
+	}
+	if s.Deprecated {
+		req += ", deprecated"
+	}
+	g.Add(`| `, name, ` | `, typ, ` | `, req, ` | `, getText(desc), ` |`)
+}
+
 func (g *Generator) RegSchemas() {
 	for _, s := range g.spec.Schemas {
 		tmp := s
@@ -172,6 +285,26 @@ func (g *Generator) RegSchemas() {
 	}
 }
 
+func getMethodColor(method string) string {
+	switch method {
+	case "POST":
+		return "darkorange"
+	case "PUT", "PATCH":
+		return "darkblue"
+	case "DELETE":
+		return "darkred"
+	case "HEAD", "OPTIONS":
+		return "dimgray"
+	default:
+		return "darkgreen"
+	}
+}
+
+// getText makes multi-line text fit into a table cell
+func getText(text string) string {
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
 func getArr(arr bool) string {
 	if arr {
 		return "[]"
@@ -179,6 +312,43 @@ func getArr(arr bool) string {
 	return ""
 }
 
+func getConstraints(c parser.Constraints) string {
+	res := []string{}
+	if c.Min != nil {
+		op := ">= "
+		if c.ExclusiveMin {
+			op = "> "
+		}
+		res = append(res, op+strconv.FormatFloat(*c.Min, 'f', -1, 64))
+	}
+	if c.Max != nil {
+		op := "<= "
+		if c.ExclusiveMax {
+			op = "< "
+		}
+		res = append(res, op+strconv.FormatFloat(*c.Max, 'f', -1, 64))
+	}
+	if c.MinLength != nil {
+		res = append(res, "min length "+strconv.FormatInt(*c.MinLength, 10))
+	}
+	if c.MaxLength != nil {
+		res = append(res, "max length "+strconv.FormatInt(*c.MaxLength, 10))
+	}
+	if c.Pattern != "" {
+		res = append(res, "pattern `"+c.Pattern+"`")
+	}
+	if c.MinItems != nil {
+		res = append(res, "min items "+strconv.FormatInt(*c.MinItems, 10))
+	}
+	if c.MaxItems != nil {
+		res = append(res, "max items "+strconv.FormatInt(*c.MaxItems, 10))
+	}
+	if c.UniqueItems {
+		res = append(res, "unique items")
+	}
+	return strings.Join(res, ", ")
+}
+
 func getType(t parser.Type) (string, error) {
 	switch t {
 	case parser.TypeAny:
@@ -199,8 +369,30 @@ func getType(t parser.Type) (string, error) {
 		return "string", nil
 	case parser.TypeUUID:
 		return "string (uuid)", nil
+	case parser.TypeDate:
+		return "string (date)", nil
+	case parser.TypeDateTime:
+		return "string (date-time)", nil
+	case parser.TypeTime:
+		return "string (time)", nil
+	case parser.TypeDuration:
+		return "string (duration)", nil
+	case parser.TypeEmail:
+		return "string (email)", nil
+	case parser.TypeURI:
+		return "string (uri)", nil
+	case parser.TypeIPv4:
+		return "string (ipv4)", nil
+	case parser.TypeIPv6:
+		return "string (ipv6)", nil
+	case parser.TypeByte:
+		return "string (base64)", nil
+	case parser.TypeDecimal:
+		return "string (decimal)", nil
 	case parser.TypeFile:
 		return "file", nil
+	case parser.TypeEnum:
+		return "enum", nil
 	default:
 		return "", fmt.Errorf("unknow type `%s`", string(t))
 	}
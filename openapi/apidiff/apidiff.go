@@ -0,0 +1,633 @@
+package apidiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Kegian/agen/openapi/parser"
+)
+
+// Codes of changes
+const (
+	CodeEndpointRemoved    = "endpoint-removed"
+	CodeEndpointAdded      = "endpoint-added"
+	CodeEndpointDeprecated = "endpoint-deprecated"
+	CodeSecurityChanged    = "security-changed"
+	CodeParamAdded         = "param-added"
+	CodeParamRemoved       = "param-removed"
+	CodeFieldAdded         = "field-added"
+	CodeFieldRemoved       = "field-removed"
+	CodeFieldRequired      = "field-required"
+	CodeFieldOptional      = "field-optional"
+	CodeTypeChanged        = "type-changed"
+	CodeNullableChanged    = "nullable-changed"
+	CodeEnumNarrowed       = "enum-narrowed"
+	CodeEnumWidened        = "enum-widened"
+	CodeVariantsChanged    = "variants-changed"
+	CodeConstraintsChanged = "constraints-changed"
+	CodeDefaultChanged     = "default-changed"
+	CodeBodyAdded          = "body-added"
+	CodeBodyRemoved        = "body-removed"
+	CodeContentTypeChanged = "content-type-changed"
+	CodeStatusChanged      = "status-changed"
+	CodeResponseAdded      = "response-added"
+	CodeResponseRemoved    = "response-removed"
+)
+
+// Change is a difference between two versions of the API
+type Change struct {
+	Endpoint string `json:"endpoint"`
+	Location string `json:"location,omitempty"`
+	Code     string `json:"code"`
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+func (c *Change) String() string {
+	res := c.Endpoint
+	if c.Location != "" {
+		res += " " + c.Location
+	}
+	return res + ": " + c.Message
+}
+
+// Report is a list of changes, endpoints keep the order of the new document, removed endpoints are the last
+type Report []Change
+
+func (r Report) HasBreaking() bool {
+	for _, c := range r {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking returns breaking changes only
+func (r Report) Breaking() Report {
+	res := Report{}
+	for _, c := range r {
+		if c.Breaking {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// NonBreaking returns non-breaking changes only
+func (r Report) NonBreaking() Report {
+	res := Report{}
+	for _, c := range r {
+		if !c.Breaking {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// direction of the data, changes are breaking if data sent by clients is rejected
+// or data received by clients is not expected
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+// Compare returns changes between documents, documents should be fully parsed (with resolved embeds)
+func Compare(oldDoc, newDoc parser.Document) Report {
+	c := &comparer{
+		old: schemasByName(oldDoc.Schemas),
+		new: schemasByName(newDoc.Schemas),
+	}
+
+	oldMethods := map[string]parser.Method{}
+	for _, m := range oldDoc.API.Methods {
+		oldMethods[endpointKey(m)] = m
+	}
+	found := map[string]struct{}{}
+	for _, m := range newDoc.API.Methods {
+		key := endpointKey(m)
+		old, ok := oldMethods[key]
+		c.endpoint = m.Method + " " + m.Path
+		if !ok {
+			c.add("", CodeEndpointAdded, false, "endpoint is added")
+			continue
+		}
+		found[key] = struct{}{}
+		c.method(old, m, oldDoc.Settings, newDoc.Settings)
+	}
+	for _, m := range oldDoc.API.Methods {
+		if _, ok := found[endpointKey(m)]; !ok {
+			c.endpoint = m.Method + " " + m.Path
+			c.add("", CodeEndpointRemoved, true, "endpoint is removed")
+		}
+	}
+
+	return c.changes
+}
+
+var pathParamRe = regexp.MustCompile(`\{[^}]*\}`)
+
+// endpointKey ignores names of path params, renaming them doesn't change the endpoint for clients
+func endpointKey(m parser.Method) string {
+	return m.Method + " " + pathParamRe.ReplaceAllString(m.Path, "{}")
+}
+
+func schemasByName(schemas []parser.Schema) map[string]*parser.Schema {
+	res := map[string]*parser.Schema{}
+	for i := range schemas {
+		res[schemas[i].Name] = &schemas[i]
+	}
+	return res
+}
+
+type comparer struct {
+	old, new map[string]*parser.Schema
+	changes  Report
+	endpoint string
+	visited  map[string]struct{} // Compared references of the current endpoint
+}
+
+func (c *comparer) add(loc, code string, breaking bool, msg string) {
+	c.changes = append(c.changes, Change{
+		Endpoint: c.endpoint,
+		Location: loc,
+		Code:     code,
+		Breaking: breaking,
+		Message:  msg,
+	})
+}
+
+func (c *comparer) method(old, new parser.Method, oldSettings, newSettings parser.Settings) {
+	c.visited = map[string]struct{}{}
+
+	if !old.Deprecated && new.Deprecated {
+		c.add("", CodeEndpointDeprecated, false, "endpoint is deprecated")
+	}
+	c.security(effectiveSecurity(old, oldSettings), effectiveSecurity(new, newSettings))
+
+	// Path params are compared by position, their names are not visible to clients
+	for i := range old.Request.Params {
+		if i < len(new.Request.Params) {
+			p := new.Request.Params[i]
+			c.value("request.params."+p.Name, old.Request.Params[i], p, request)
+		}
+	}
+	c.params("request.query", old.Request.Query, new.Request.Query)
+	c.params("request.headers", old.Request.Headers, new.Request.Headers)
+	c.params("request.cookies", old.Request.Cookies, new.Request.Cookies)
+	c.requestBody(old.Request.Body, new.Request.Body)
+
+	oldCode, newCode := statusCode(old.Response.Code), statusCode(new.Response.Code)
+	if oldCode != newCode {
+		c.add("response", CodeStatusChanged, true, fmt.Sprintf("success status is changed from %s to %s", oldCode, newCode))
+	}
+	c.fields("response.headers", old.Response.Headers, new.Response.Headers, response)
+	c.responseBody("response.body", old.Response.Body, new.Response.Body, true)
+	for _, code := range old.Response.SortedCodes() {
+		body, ok := new.Response.Codes[code]
+		if !ok {
+			c.add("response."+code, CodeResponseRemoved, false, "response is removed")
+			continue
+		}
+		c.responseBody("response."+code, old.Response.Codes[code], body, false)
+	}
+	for _, code := range new.Response.SortedCodes() {
+		if _, ok := old.Response.Codes[code]; !ok {
+			c.add("response."+code, CodeResponseAdded, false, "response is added")
+		}
+	}
+	switch {
+	case old.Response.Default != nil && new.Response.Default == nil:
+		c.add("response.default", CodeResponseRemoved, false, "response is removed")
+	case old.Response.Default == nil && new.Response.Default != nil:
+		c.add("response.default", CodeResponseAdded, false, "response is added")
+	case old.Response.Default != nil:
+		c.responseBody("response.default", old.Response.Default, new.Response.Default, false)
+	}
+}
+
+func statusCode(code string) string {
+	if code == "" {
+		return "200"
+	}
+	return code
+}
+
+// effectiveSecurity returns requirements of the method, empty requirement means public access
+func effectiveSecurity(m parser.Method, settings parser.Settings) []string {
+	reqs := m.Security
+	if reqs == nil {
+		reqs = settings.Security
+	}
+	if len(reqs) == 0 {
+		return []string{"{}"}
+	}
+	res := make([]string, 0, len(reqs))
+	for _, r := range reqs {
+		items := []string{}
+		for _, name := range r.Names() {
+			scopes := append([]string{}, r[name]...)
+			sort.Strings(scopes)
+			items = append(items, name+"["+strings.Join(scopes, ",")+"]")
+		}
+		res = append(res, "{"+strings.Join(items, ",")+"}")
+	}
+	return res
+}
+
+// security reports removed alternatives as breaking, clients using them are rejected
+func (c *comparer) security(old, new []string) {
+	removed, added := difference(old, new), difference(new, old)
+	if len(removed) != 0 {
+		c.add("security", CodeSecurityChanged, true, "security requirements are removed: "+strings.Join(removed, ", "))
+	}
+	if len(added) != 0 {
+		c.add("security", CodeSecurityChanged, false, "security requirements are added: "+strings.Join(added, ", "))
+	}
+}
+
+func (c *comparer) params(loc string, old, new []parser.Schema) {
+	oldParams := map[string]parser.Schema{}
+	for _, p := range old {
+		oldParams[strings.ToLower(p.Name)] = p
+	}
+	for _, p := range new {
+		o, ok := oldParams[strings.ToLower(p.Name)]
+		if !ok {
+			if p.Optional {
+				c.add(loc+"."+p.Name, CodeParamAdded, false, "optional parameter is added")
+			} else {
+				c.add(loc+"."+p.Name, CodeParamAdded, true, "required parameter is added")
+			}
+			continue
+		}
+		delete(oldParams, strings.ToLower(p.Name))
+		c.field(loc+"."+p.Name, o, p, request)
+	}
+	for _, p := range old {
+		if _, ok := oldParams[strings.ToLower(p.Name)]; ok {
+			c.add(loc+"."+p.Name, CodeParamRemoved, false, "parameter is removed, it is ignored now")
+		}
+	}
+}
+
+func (c *comparer) requestBody(old, new *parser.Schema) {
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		c.add("request.body", CodeBodyAdded, !new.Optional, "request body is added")
+		return
+	case new == nil:
+		c.add("request.body", CodeBodyRemoved, false, "request body is removed, it is ignored now")
+		return
+	}
+	c.contentTypes("request.body", old.ContentTypes, new.ContentTypes, request)
+	c.field("request.body", *old, *new, request)
+}
+
+// responseBody compares bodies of responses, nil body is an empty response
+func (c *comparer) responseBody(loc string, old, new *parser.Schema, success bool) {
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		c.add(loc, CodeBodyAdded, false, "response body is added")
+		return
+	case new == nil:
+		c.add(loc, CodeBodyRemoved, success, "response body is removed")
+		return
+	}
+	c.contentTypes(loc, old.ContentTypes, new.ContentTypes, response)
+	c.value(loc, *old, *new, response)
+}
+
+func (c *comparer) contentTypes(loc string, old, new []string, dir direction) {
+	if len(old) == 0 {
+		old = []string{parser.ContentTypeJSON}
+	}
+	if len(new) == 0 {
+		new = []string{parser.ContentTypeJSON}
+	}
+	if removed := difference(old, new); len(removed) != 0 {
+		c.add(loc, CodeContentTypeChanged, true, "content types are removed: "+strings.Join(removed, ", "))
+	}
+	if added := difference(new, old); len(added) != 0 {
+		// New response content type is breaking if it is the only one
+		c.add(loc, CodeContentTypeChanged, dir == response && len(difference(old, new)) == len(old),
+			"content types are added: "+strings.Join(added, ", "))
+	}
+}
+
+// fields compares fields of objects, fields are matched by name
+func (c *comparer) fields(loc string, old, new []parser.Schema, dir direction) {
+	oldFields := map[string]parser.Schema{}
+	for _, f := range old {
+		oldFields[f.Name] = f
+	}
+	for _, f := range new {
+		o, ok := oldFields[f.Name]
+		if !ok {
+			switch {
+			case dir == request && !f.Optional && f.Default == "":
+				c.add(loc+"."+f.Name, CodeFieldAdded, true, "required field is added")
+			default:
+				c.add(loc+"."+f.Name, CodeFieldAdded, false, "field is added")
+			}
+			continue
+		}
+		delete(oldFields, f.Name)
+		c.field(loc+"."+f.Name, o, f, dir)
+	}
+	for _, f := range old {
+		if _, ok := oldFields[f.Name]; !ok {
+			continue
+		}
+		switch {
+		case dir == response && !f.Optional:
+			c.add(loc+"."+f.Name, CodeFieldRemoved, true, "required field is removed")
+		case dir == response:
+			c.add(loc+"."+f.Name, CodeFieldRemoved, false, "optional field is removed")
+		default:
+			c.add(loc+"."+f.Name, CodeFieldRemoved, false, "field is removed, it is ignored now")
+		}
+	}
+}
+
+// field compares optionality and default value of the field and its value
+func (c *comparer) field(loc string, old, new parser.Schema, dir direction) {
+	switch {
+	case old.Optional && !new.Optional:
+		c.add(loc, CodeFieldRequired, dir == request && new.Default == "", "field becomes required")
+	case !old.Optional && new.Optional:
+		c.add(loc, CodeFieldOptional, dir == response, "field becomes optional")
+	}
+	if old.Default != new.Default {
+		c.add(loc, CodeDefaultChanged, false, fmt.Sprintf("default value is changed from `%s` to `%s`", old.Default, new.Default))
+	}
+	c.value(loc, old, new, dir)
+}
+
+// value compares types of values, references are compared by the content of the schemas
+func (c *comparer) value(loc string, old, new parser.Schema, dir direction) {
+	if old.Nullable != new.Nullable {
+		// Null is rejected in requests or unexpected in responses
+		breaking := dir == request && old.Nullable || dir == response && new.Nullable
+		if new.Nullable {
+			c.add(loc, CodeNullableChanged, breaking, "value becomes nullable")
+		} else {
+			c.add(loc, CodeNullableChanged, breaking, "value becomes non-nullable")
+		}
+	}
+
+	if old.IsArray || new.IsArray {
+		if old.IsArray != new.IsArray {
+			c.typeChanged(loc, old, new)
+			return
+		}
+		c.constraints(loc, itemsConstraints(old.Constraints), itemsConstraints(new.Constraints), dir)
+		c.value(loc+"[]", item(old), item(new), dir)
+		return
+	}
+	if old.IsMap || new.IsMap {
+		if old.IsMap != new.IsMap {
+			c.typeChanged(loc, old, new)
+			return
+		}
+		old.IsMap, new.IsMap = false, false
+		old.Nullable, new.Nullable = false, false
+		c.value(loc+"{}", old, new, dir)
+		return
+	}
+
+	if old.Type.IsRef() || new.Type.IsRef() {
+		// References with added fields (merged common body) are compared by content each time
+		if len(old.Fields) == 0 && len(new.Fields) == 0 {
+			key := fmt.Sprintf("%s|%s|%d", old.Type, new.Type, dir)
+			if _, ok := c.visited[key]; ok {
+				return
+			}
+			c.visited[key] = struct{}{}
+		}
+		var ok bool
+		if old, ok = deref(old, c.old); !ok {
+			return
+		}
+		if new, ok = deref(new, c.new); !ok {
+			return
+		}
+		c.value(loc, old, new, dir)
+		return
+	}
+
+	if old.Type != new.Type || old.Format != new.Format && old.Type == parser.TypeFile {
+		c.typeChanged(loc, old, new)
+		return
+	}
+
+	switch {
+	case old.Type == parser.TypeEnum:
+		c.enum(loc, old.Enum, new.Enum, dir)
+	case old.Type.IsUnion():
+		c.variants(loc, old, new, dir)
+	case old.Type == parser.TypeObject:
+		c.fields(loc, old.Fields, new.Fields, dir)
+	}
+	c.constraints(loc, valueConstraints(old.Constraints), valueConstraints(new.Constraints), dir)
+}
+
+// deref returns referenced schema, fields added to the reference are appended to its fields
+func deref(s parser.Schema, schemas map[string]*parser.Schema) (parser.Schema, bool) {
+	if !s.Type.IsRef() {
+		return s, true
+	}
+	ref, ok := schemas[s.Type.Name()]
+	if !ok {
+		return parser.Schema{}, false
+	}
+	res := *ref
+	if len(s.Fields) != 0 {
+		res.Fields = append(append([]parser.Schema{}, ref.Fields...), s.Fields...)
+	}
+	return res, true
+}
+
+func item(s parser.Schema) parser.Schema {
+	s.IsArray = false
+	s.Nullable = false
+	s.Constraints = valueConstraints(s.Constraints)
+	return s
+}
+
+func (c *comparer) typeChanged(loc string, old, new parser.Schema) {
+	c.add(loc, CodeTypeChanged, true, fmt.Sprintf("type is changed from `%s` to `%s`", typeName(old), typeName(new)))
+}
+
+func typeName(s parser.Schema) string {
+	res := string(s.Type)
+	if s.Type == parser.TypeFile && s.Format != "" {
+		res += "(" + s.Format + ")"
+	}
+	if s.IsMap {
+		res = "map[" + res + "]"
+	}
+	if s.IsArray {
+		res += "[]"
+	}
+	return res
+}
+
+// enum reports removed values in requests and added values in responses as breaking
+func (c *comparer) enum(loc string, old, new []string, dir direction) {
+	if removed := difference(old, new); len(removed) != 0 {
+		c.add(loc, CodeEnumNarrowed, dir == request, "enum values are removed: "+strings.Join(removed, ", "))
+	}
+	if added := difference(new, old); len(added) != 0 {
+		c.add(loc, CodeEnumWidened, dir == response, "enum values are added: "+strings.Join(added, ", "))
+	}
+}
+
+func (c *comparer) variants(loc string, old, new parser.Schema, dir direction) {
+	if old.Discriminator.Property != new.Discriminator.Property {
+		c.add(loc, CodeVariantsChanged, true, fmt.Sprintf("discriminator is changed from `%s` to `%s`",
+			old.Discriminator.Property, new.Discriminator.Property))
+	}
+	oldVariants, newVariants := variantNames(old), variantNames(new)
+	for i, name := range oldVariants {
+		for j := range newVariants {
+			if newVariants[j] == name {
+				c.value(loc+"("+name+")", parser.Schema{Type: old.Variants[i]}, parser.Schema{Type: new.Variants[j]}, dir)
+			}
+		}
+	}
+	if removed := difference(oldVariants, newVariants); len(removed) != 0 {
+		c.add(loc, CodeVariantsChanged, dir == request, "union variants are removed: "+strings.Join(removed, ", "))
+	}
+	if added := difference(newVariants, oldVariants); len(added) != 0 {
+		c.add(loc, CodeVariantsChanged, dir == response, "union variants are added: "+strings.Join(added, ", "))
+	}
+}
+
+// variantNames returns discriminator values of the variants, types are used without discriminator
+func variantNames(s parser.Schema) []string {
+	values := map[parser.Type]string{}
+	for _, m := range s.Discriminator.Mapping {
+		values[m.Type] = m.Value
+	}
+	res := make([]string, 0, len(s.Variants))
+	for _, v := range s.Variants {
+		if value, ok := values[v]; ok {
+			res = append(res, value)
+		} else {
+			res = append(res, string(v))
+		}
+	}
+	return res
+}
+
+func valueConstraints(c parser.Constraints) parser.Constraints {
+	return parser.Constraints{
+		Min:          c.Min,
+		Max:          c.Max,
+		ExclusiveMin: c.ExclusiveMin,
+		ExclusiveMax: c.ExclusiveMax,
+		MinLength:    c.MinLength,
+		MaxLength:    c.MaxLength,
+		Pattern:      c.Pattern,
+	}
+}
+
+func itemsConstraints(c parser.Constraints) parser.Constraints {
+	return parser.Constraints{
+		MinItems:    c.MinItems,
+		MaxItems:    c.MaxItems,
+		UniqueItems: c.UniqueItems,
+	}
+}
+
+// constraints reports narrowed constraints in requests and widened constraints in responses as breaking,
+// generated clients validate responses
+func (c *comparer) constraints(loc string, old, new parser.Constraints, dir direction) {
+	narrowed, widened := []string{}, []string{}
+	check := func(name string, oldVal, newVal *float64, isMin bool) {
+		switch {
+		case oldVal == nil && newVal == nil:
+		case oldVal == nil:
+			narrowed = append(narrowed, name+" is set to "+formatFloat(*newVal))
+		case newVal == nil:
+			widened = append(widened, name+" is removed")
+		case *oldVal == *newVal:
+		case *newVal > *oldVal == isMin:
+			narrowed = append(narrowed, fmt.Sprintf("%s is changed from %s to %s", name, formatFloat(*oldVal), formatFloat(*newVal)))
+		default:
+			widened = append(widened, fmt.Sprintf("%s is changed from %s to %s", name, formatFloat(*oldVal), formatFloat(*newVal)))
+		}
+	}
+	flag := func(name string, oldVal, newVal bool) {
+		switch {
+		case !oldVal && newVal:
+			narrowed = append(narrowed, name+" is set")
+		case oldVal && !newVal:
+			widened = append(widened, name+" is removed")
+		}
+	}
+
+	check("min", old.Min, new.Min, true)
+	check("max", old.Max, new.Max, false)
+	flag("exclusive_min", old.ExclusiveMin, new.ExclusiveMin)
+	flag("exclusive_max", old.ExclusiveMax, new.ExclusiveMax)
+	check("min_length", intToFloat(old.MinLength), intToFloat(new.MinLength), true)
+	check("max_length", intToFloat(old.MaxLength), intToFloat(new.MaxLength), false)
+	check("min_items", intToFloat(old.MinItems), intToFloat(new.MinItems), true)
+	check("max_items", intToFloat(old.MaxItems), intToFloat(new.MaxItems), false)
+	flag("unique_items", old.UniqueItems, new.UniqueItems)
+	switch {
+	case old.Pattern == new.Pattern:
+	case old.Pattern == "":
+		narrowed = append(narrowed, "pattern is set to `"+new.Pattern+"`")
+	case new.Pattern == "":
+		widened = append(widened, "pattern is removed")
+	default:
+		// Changed pattern can both narrow and widen values
+		c.add(loc, CodeConstraintsChanged, true, fmt.Sprintf("pattern is changed from `%s` to `%s`", old.Pattern, new.Pattern))
+	}
+
+	if len(narrowed) != 0 {
+		c.add(loc, CodeConstraintsChanged, dir == request, "constraints are narrowed: "+strings.Join(narrowed, ", "))
+	}
+	if len(widened) != 0 {
+		c.add(loc, CodeConstraintsChanged, dir == response, "constraints are widened: "+strings.Join(widened, ", "))
+	}
+}
+
+func intToFloat(v *int64) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// difference returns values of a which are not in b
+func difference(a, b []string) []string {
+	set := map[string]struct{}{}
+	for _, v := range b {
+		set[v] = struct{}{}
+	}
+	res := []string{}
+	for _, v := range a {
+		if _, ok := set[v]; !ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package apidiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kegian/agen/openapi/parser"
+)
+
+func mustParse(t *testing.T, data string) parser.Document {
+	t.Helper()
+	doc, err := parser.ParseDocument([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestCompare(t *testing.T) {
+	oldDoc := mustParse(t, `
+api:
+  users:
+    GET /users:
+      request:
+        query:
+          limit: int32?
+          offset: int32?
+      response:
+        body: $User[]
+        404: empty
+    POST /users:
+      request:
+        body: $User
+      response:
+        body: $User
+    DELETE /users/{id}:
+schemas:
+  User:
+    id: int64
+    name: string
+    email: string?
+    status: enum(active,blocked)
+`)
+	newDoc := mustParse(t, `
+api:
+  users:
+    GET /users:
+      deprecated: true
+      request:
+        query:
+          limit: int32{max=100}?
+          page: int32
+      response:
+        body: $User[]
+    POST /users:
+      request:
+        body: $User
+      response:
+        201: $User
+    PUT /users/{user_id}:
+schemas:
+  User:
+    id: int64
+    name: string?
+    email: string!null?
+    status: enum(active,blocked,deleted)
+    age: int32? = 18
+`)
+
+	// Changes of requests are breaking if clients are rejected, changes of responses if clients get unexpected data
+	want := []string{
+		"GET /users: endpoint is deprecated [endpoint-deprecated]",
+		"breaking GET /users request.query.limit: constraints are narrowed: max is set to 100 [constraints-changed]",
+		"breaking GET /users request.query.page: required parameter is added [param-added]",
+		"GET /users request.query.offset: parameter is removed, it is ignored now [param-removed]",
+		"breaking GET /users response.body[].name: field becomes optional [field-optional]",
+		"breaking GET /users response.body[].email: value becomes nullable [nullable-changed]",
+		"breaking GET /users response.body[].status: enum values are added: deleted [enum-widened]",
+		"GET /users response.body[].age: field is added [field-added]",
+		"GET /users response.404: response is removed [response-removed]",
+		"POST /users request.body.name: field becomes optional [field-optional]",
+		"POST /users request.body.email: value becomes nullable [nullable-changed]",
+		"POST /users request.body.status: enum values are added: deleted [enum-widened]",
+		"POST /users request.body.age: field is added [field-added]",
+		"breaking POST /users response: success status is changed from 200 to 201 [status-changed]",
+		"breaking POST /users response.body.name: field becomes optional [field-optional]",
+		"breaking POST /users response.body.email: value becomes nullable [nullable-changed]",
+		"breaking POST /users response.body.status: enum values are added: deleted [enum-widened]",
+		"POST /users response.body.age: field is added [field-added]",
+		"PUT /users/{user_id}: endpoint is added [endpoint-added]",
+		"breaking DELETE /users/{id}: endpoint is removed [endpoint-removed]",
+	}
+	report := Compare(oldDoc, newDoc)
+	got := []string{}
+	for _, c := range report {
+		s := c.String() + " [" + c.Code + "]"
+		if c.Breaking {
+			s = "breaking " + s
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !report.HasBreaking() || len(report.Breaking()) != 10 || len(report.NonBreaking()) != 10 {
+		t.Errorf("got %d breaking and %d non-breaking changes, want 10 and 10", len(report.Breaking()), len(report.NonBreaking()))
+	}
+}
+
+func TestCompareRenamedPathParams(t *testing.T) {
+	oldDoc := mustParse(t, `
+api:
+  users:
+    GET /users/{id}:
+      response:
+        body: $User
+schemas:
+  User:
+    id: int64
+    friend: $User?
+`)
+	newDoc := mustParse(t, `
+api:
+  users:
+    GET /users/{user_id}:
+      response:
+        body: $User
+schemas:
+  User:
+    id: int64
+    friend: $User?
+`)
+	// Renamed path params and recursive schemas produce no changes
+	if report := Compare(oldDoc, newDoc); len(report) != 0 {
+		t.Errorf("got changes %v, want none", report)
+	}
+}
@@ -0,0 +1,274 @@
+package formatter
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options of the formatter
+type Options struct {
+	SortSchemas bool // Sort schemas by name
+}
+
+var methodRe = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS) /`)
+
+// Format rewrites api file in canonical layout: indentation of 2 spaces, single quoted method keys,
+// empty lines between sections, tags, methods and schemas and aligned line comments.
+// Comments are kept as they contain descriptions and examples
+func Format(data []byte, opts Options) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+
+	markFootComments(&doc)
+
+	root := doc.Content[0]
+	if root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			switch root.Content[i].Value {
+			case "api":
+				formatAPI(root.Content[i+1])
+			case "schemas":
+				if opts.SortSchemas {
+					sortPairs(root.Content[i+1])
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	literal := literalLines(lines)
+	foot := footLines(lines, literal)
+	indentComments(lines, literal, foot)
+	lines, literal = spaceBlocks(lines, literal, foot)
+	alignComments(lines, literal)
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// footMarker marks lines of foot comments before encoding, encoder places them under their nodes,
+// e.g. under the last field of a schema, and they keep their indentation
+const footMarker = "#agen:foot:"
+
+func markFootComments(n *yaml.Node) {
+	if n.FootComment != "" {
+		lines := strings.Split(n.FootComment, "\n")
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), "#") {
+				lines[i] = footMarker + strings.TrimSpace(l)
+			}
+		}
+		n.FootComment = strings.Join(lines, "\n")
+	}
+	for _, c := range n.Content {
+		markFootComments(c)
+	}
+}
+
+// footLines returns marked lines of foot comments, markers are removed
+func footLines(lines []string, literal []bool) []bool {
+	res := make([]bool, len(lines))
+	for i, l := range lines {
+		if literal[i] {
+			continue
+		}
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, footMarker) {
+			lines[i] = l[:indentOf(l)] + strings.TrimPrefix(trimmed, footMarker)
+			res[i] = true
+		}
+	}
+	return res
+}
+
+// formatAPI quotes method keys of tags
+func formatAPI(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(n.Content); i += 2 {
+		tag := n.Content[i]
+		if tag.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j < len(tag.Content); j += 2 {
+			key := tag.Content[j]
+			if key.Kind == yaml.ScalarNode && methodRe.MatchString(key.Value) {
+				key.Style = yaml.SingleQuotedStyle
+			}
+		}
+	}
+}
+
+// sortPairs sorts keys of the mapping, comments are moved with their keys
+func sortPairs(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	type pair struct{ key, value *yaml.Node }
+	pairs := make([]pair, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, pair{n.Content[i], n.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].key.Value < pairs[j].key.Value
+	})
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p.key, p.value)
+	}
+}
+
+func indentOf(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+func isComment(l string) bool {
+	return strings.HasPrefix(strings.TrimSpace(l), "#")
+}
+
+var blockScalarRe = regexp.MustCompile(`(^|[:-]\s)[|>][-+0-9]*$`)
+
+// literalLines marks lines of block scalars, their content is kept as is
+func literalLines(lines []string) []bool {
+	res := make([]bool, len(lines))
+	for i := 0; i < len(lines); i++ {
+		code := strings.TrimRight(lines[i][:codeEnd(lines[i])], " ")
+		if !blockScalarRe.MatchString(strings.TrimSpace(code)) {
+			continue
+		}
+		indent := indentOf(lines[i])
+		for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || indentOf(lines[i+1]) > indent) {
+			i++
+			res[i] = true
+		}
+	}
+	return res
+}
+
+// indentComments indents comment lines as the next line, encoder can misplace head comments of nested keys,
+// foot comments are kept as they belong to the previous lines
+func indentComments(lines []string, literal, foot []bool) {
+	indent := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		switch {
+		case literal[i] || foot[i] || strings.TrimSpace(lines[i]) == "":
+		case isComment(lines[i]):
+			lines[i] = strings.Repeat(" ", indent) + strings.TrimSpace(lines[i])
+		default:
+			indent = indentOf(lines[i])
+		}
+	}
+}
+
+// spaceBlocks separates top level sections, tags, methods and schemas with empty lines,
+// the empty line is added before the head comment of the block unless the block is the first child,
+// foot comments of the previous block stay above the empty line
+func spaceBlocks(lines []string, literal, foot []bool) ([]string, []bool) {
+	res := make([]string, 0, len(lines))
+	resLiteral := make([]bool, 0, len(lines))
+	resFoot := make([]bool, 0, len(lines))
+	section := ""
+	for i, l := range lines {
+		indent := indentOf(l)
+		if !literal[i] && indent == 0 && l != "" && !isComment(l) {
+			section = strings.TrimSuffix(strings.Fields(l)[0], ":")
+		}
+		key := strings.TrimSpace(l)
+		isBlock := indent == 0 ||
+			indent == 2 && (section == "api" || section == "schemas") ||
+			indent == 4 && section == "api" && (strings.HasPrefix(key, "'") || strings.HasPrefix(key, "_common:"))
+		if isBlock && !literal[i] && l != "" && !isComment(l) {
+			start := len(res)
+			for start > 0 && isComment(res[start-1]) && !resLiteral[start-1] && !resFoot[start-1] && indentOf(res[start-1]) == indent {
+				start--
+			}
+			if start > 0 && res[start-1] != "" && indentOf(res[start-1]) >= indent {
+				res = append(res[:start], append([]string{""}, res[start:]...)...)
+				resLiteral = append(resLiteral[:start], append([]bool{false}, resLiteral[start:]...)...)
+				resFoot = append(resFoot[:start], append([]bool{false}, resFoot[start:]...)...)
+			}
+		}
+		res = append(res, l)
+		resLiteral = append(resLiteral, literal[i])
+		resFoot = append(resFoot, foot[i])
+	}
+	return res, resLiteral
+}
+
+// alignComments aligns line comments of consecutive lines with the same indentation
+func alignComments(lines []string, literal []bool) {
+	for start := 0; start < len(lines); {
+		end := start + 1
+		if !literal[start] && lines[start] != "" && !isComment(lines[start]) {
+			for end < len(lines) && !literal[end] && lines[end] != "" && !isComment(lines[end]) &&
+				indentOf(lines[end]) == indentOf(lines[start]) {
+				end++
+			}
+		}
+
+		if isComment(lines[start]) {
+			start = end
+			continue
+		}
+		width := 0
+		for _, l := range lines[start:end] {
+			if c := codeEnd(l); c < len(l) {
+				if w := len(strings.TrimRight(l[:c], " ")); w > width {
+					width = w
+				}
+			}
+		}
+		for i := start; i < end; i++ {
+			l := lines[i]
+			if c := codeEnd(l); c < len(l) && !literal[i] {
+				code := strings.TrimRight(l[:c], " ")
+				lines[i] = code + strings.Repeat(" ", width-len(code)+1) + l[c:]
+			}
+		}
+		start = end
+	}
+}
+
+// codeEnd returns the position of the line comment or the length of the line,
+// quotes are taken into account only at the start of scalars
+func codeEnd(l string) int {
+	var quote byte
+	prev := byte(' ')
+	for i := 0; i < len(l); i++ {
+		ch := l[i]
+		switch {
+		case quote == '"' && ch == '\\':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case (ch == '\'' || ch == '"') && strings.IndexByte(" :-[{,", prev) >= 0 && (i == 0 || l[i-1] == ' ' || strings.IndexByte("[{,", l[i-1]) >= 0):
+			quote = ch
+		case ch == '#' && (i == 0 || l[i-1] == ' '):
+			return i
+		}
+		if ch != ' ' {
+			prev = ch
+		}
+	}
+	return len(l)
+}
@@ -0,0 +1,208 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/Kegian/agen/openapi/gen"
+	"github.com/Kegian/agen/openapi/parser"
+)
+
+func TestFormat(t *testing.T) {
+	data := `settings:
+    title: Users
+api:
+    users:
+        "GET /users": # List users
+            response:
+                body: $User[]
+        POST /users:
+            request:
+                body: $User
+schemas:
+    # User of the service
+    User:
+        id: int64 # ID (1)
+        name: string   # Full name
+        bio: |
+            Multi-line # not a comment
+              kept as is
+    Error:
+        message: string
+`
+	want := `settings:
+  title: Users
+
+api:
+  users:
+    'GET /users': # List users
+      response:
+        body: $User[]
+
+    'POST /users':
+      request:
+        body: $User
+
+schemas:
+  # User of the service
+  User:
+    id: int64    # ID (1)
+    name: string # Full name
+    bio: |
+      Multi-line # not a comment
+        kept as is
+
+  Error:
+    message: string
+`
+	got, err := Format([]byte(data), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Formatting is idempotent
+	again, err := Format(got, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(got) {
+		t.Errorf("got after second format:\n%s\nwant:\n%s", again, got)
+	}
+}
+
+func TestFormatSortSchemas(t *testing.T) {
+	data := `schemas:
+  # User of the service
+  User:
+    id: int64
+  Error:
+    message: string # Message
+`
+	want := `schemas:
+  Error:
+    message: string # Message
+
+  # User of the service
+  User:
+    id: int64
+`
+	got, err := Format([]byte(data), Options{SortSchemas: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format([]byte("schemas: ["), Options{}); err == nil {
+		t.Error("got no error for incorrect YAML")
+	}
+	if got, err := Format([]byte(""), Options{}); err != nil || len(got) != 0 {
+		t.Errorf("got %q (%v), want empty document as is", got, err)
+	}
+}
+
+const commentsDoc = `settings:
+  title: Users
+  version: 1.0.0
+api:
+  # Users of the service
+  users:
+    # List of users,
+    # sorted by name
+    GET /users:
+      response:
+        body: $User[] # Users
+      # Foot comment of the method
+    POST /users:
+      request:
+        body: $User
+  # Foot comment of the tag
+
+schemas:
+  # User of the service
+  User:
+    # Identifier
+    id: int64 # ID (1)
+    name: string   # Full name (John)
+    account: # Account of the user
+      number: string
+      # Foot comment of the nested object
+    # Foot comment of the last field
+
+  # Error
+  # of the request
+  Error:
+    message: string
+  # Foot comment of the schema
+
+  Account:
+    id: int64
+`
+
+// Formatting keeps comments attached to their declarations, so descriptions are not changed
+func TestFormatKeepsSpec(t *testing.T) {
+	want := genSpec(t, []byte(commentsDoc))
+	for _, sortSchemas := range []bool{false, true} {
+		data, err := Format([]byte(commentsDoc), Options{SortSchemas: sortSchemas})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := genSpec(t, data); got != want {
+			t.Errorf("sort schemas %v: got spec:\n%s\nwant:\n%s\nformatted file:\n%s", sortSchemas, got, want, data)
+		}
+
+		again, err := Format(data, Options{SortSchemas: sortSchemas})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("sort schemas %v: got after second format:\n%s\nwant:\n%s", sortSchemas, again, data)
+		}
+	}
+}
+
+func TestFormatFootComments(t *testing.T) {
+	data := `schemas:
+  User:
+    id: int64
+    name: string
+    # Foot comment of the last field
+
+  Account:
+    id: int64
+`
+	want := `schemas:
+  Account:
+    id: int64
+
+  User:
+    id: int64
+    name: string
+    # Foot comment of the last field
+`
+	got, err := Format([]byte(data), Options{SortSchemas: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func genSpec(t *testing.T, data []byte) string {
+	t.Helper()
+	doc, err := parser.ParseDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := gen.GenerateSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
@@ -12,7 +12,37 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// Version is a version of the generated OpenAPI specification
+type Version string
+
+const (
+	Version30 Version = "3.0"
+	Version31 Version = "3.1"
+)
+
+// Format is a serialization format of the generated specification
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// Options of the generated specification, zero value is OpenAPI 3.0 in YAML
+type Options struct {
+	Version Version
+	Format  Format
+	// Ogen skips constructs which are not supported by ogen, see parser.CheckOgen
+	Ogen bool
+}
+
 func GenerateSpec(doc parser.Document) (string, error) {
+	return GenerateSpecWithOptions(doc, Options{})
+}
+
+// GenerateSpecWithOptions generates specification of the version and format,
+// paths keep the order of the API file in both formats
+func GenerateSpecWithOptions(doc parser.Document, opts Options) (string, error) {
 	spec := oa.Spec{
 		Openapi: "3.0.2",
 		Info: oa.Info{
@@ -24,12 +54,7 @@ func GenerateSpec(doc parser.Document) (string, error) {
 				URL: doc.Settings.URL,
 			},
 		},
-		Security: []map[string][]string{
-			{},
-			{
-				"bearerAuth": []string{},
-			},
-		},
+		Security: GenSecurity(doc.Settings.Security),
 	}
 
 	for _, t := range doc.API.Tags {
@@ -54,10 +79,14 @@ func GenerateSpec(doc parser.Document) (string, error) {
 	paths := oa.Paths{
 		MapOfPathItemValues: map[string]oa.PathItem{},
 	}
-	for path, methods := range parsedPaths {
+	for _, path := range pathsOrder {
 		operations := map[string]oa.Operation{}
-		for _, m := range methods {
-			operations[strings.ToLower(m.Method)] = GenOperation(m)
+		for _, m := range parsedPaths[path] {
+			op := GenOperation(m)
+			if opts.Ogen {
+				ogenEncoding(&op)
+			}
+			operations[strings.ToLower(m.Method)] = op
 		}
 		paths.MapOfPathItemValues[path] = oa.PathItem{
 			MapOfOperationValues: operations,
@@ -75,20 +104,21 @@ func GenerateSpec(doc parser.Document) (string, error) {
 		Schemas: &oa.ComponentsSchemas{
 			MapOfSchemaOrRefValues: schemas,
 		},
-		SecuritySchemes: &oa.ComponentsSecuritySchemes{
-			MapOfSecuritySchemeOrRefValues: map[string]oa.SecuritySchemeOrRef{
-				"bearerAuth": {
-					SecurityScheme: &oa.SecurityScheme{
-						HTTPSecurityScheme: &oa.HTTPSecurityScheme{
-							Scheme: "bearer",
-						},
-					},
-				},
-			},
-		},
 	}
 
-	out, err := marshalYAML(&spec, pathsOrder)
+	if len(doc.Settings.SecuritySchemes) != 0 {
+		schemes := map[string]oa.SecuritySchemeOrRef{}
+		for _, s := range doc.Settings.SecuritySchemes {
+			schemes[s.Name] = oa.SecuritySchemeOrRef{
+				SecurityScheme: GenSecurityScheme(s),
+			}
+		}
+		spec.Components.SecuritySchemes = &oa.ComponentsSecuritySchemes{
+			MapOfSecuritySchemeOrRefValues: schemes,
+		}
+	}
+
+	out, err := marshalSpec(&spec, pathsOrder, opts)
 	if err != nil {
 		return "", err
 	}
@@ -96,11 +126,97 @@ func GenerateSpec(doc parser.Document) (string, error) {
 	return string(out), nil
 }
 
+func GenSecurity(reqs []parser.SecurityRequirement) []map[string][]string {
+	if reqs == nil {
+		return nil
+	}
+	res := make([]map[string][]string, 0, len(reqs))
+	for _, r := range reqs {
+		req := map[string][]string{}
+		for name, scopes := range r {
+			req[name] = append([]string{}, scopes...)
+		}
+		res = append(res, req)
+	}
+	return res
+}
+
+func GenSecurityScheme(s parser.SecurityScheme) *oa.SecurityScheme {
+	switch s.Type {
+	case parser.SecurityTypeHTTP:
+		return &oa.SecurityScheme{
+			HTTPSecurityScheme: &oa.HTTPSecurityScheme{
+				Scheme:       s.Scheme,
+				BearerFormat: nilStr(s.BearerFormat),
+				Description:  nilStr(s.Description),
+			},
+		}
+	case parser.SecurityTypeAPIKey:
+		return &oa.SecurityScheme{
+			APIKeySecurityScheme: &oa.APIKeySecurityScheme{
+				Name:        s.Param,
+				In:          oa.APIKeySecuritySchemeIn(s.In),
+				Description: nilStr(s.Description),
+			},
+		}
+	case parser.SecurityTypeOAuth2:
+		flows := oa.OAuthFlows{}
+		for _, f := range s.Flows {
+			scopes := map[string]string{}
+			for _, sc := range f.Scopes {
+				scopes[sc.Name] = sc.Description
+			}
+			switch f.Type {
+			case parser.OAuthFlowImplicit:
+				flows.Implicit = &oa.ImplicitOAuthFlow{
+					AuthorizationURL: f.AuthorizationURL,
+					RefreshURL:       nilStr(f.RefreshURL),
+					Scopes:           scopes,
+				}
+			case parser.OAuthFlowPassword:
+				flows.Password = &oa.PasswordOAuthFlow{
+					TokenURL:   f.TokenURL,
+					RefreshURL: nilStr(f.RefreshURL),
+					Scopes:     scopes,
+				}
+			case parser.OAuthFlowClientCredentials:
+				flows.ClientCredentials = &oa.ClientCredentialsFlow{
+					TokenURL:   f.TokenURL,
+					RefreshURL: nilStr(f.RefreshURL),
+					Scopes:     scopes,
+				}
+			case parser.OAuthFlowAuthorizationCode:
+				flows.AuthorizationCode = &oa.AuthorizationCodeOAuthFlow{
+					AuthorizationURL: f.AuthorizationURL,
+					TokenURL:         f.TokenURL,
+					RefreshURL:       nilStr(f.RefreshURL),
+					Scopes:           scopes,
+				}
+			}
+		}
+		return &oa.SecurityScheme{
+			OAuth2SecurityScheme: &oa.OAuth2SecurityScheme{
+				Flows:       flows,
+				Description: nilStr(s.Description),
+			},
+		}
+	default:
+		return nil
+	}
+}
+
 func GenOperation(m parser.Method) oa.Operation {
 	op := oa.Operation{
 		ID:          nilStr(m.Name),
 		Tags:        []string{m.Tag},
 		Description: nilStr(m.Description),
+		Deprecated:  nilTrue(m.Deprecated),
+		Security:    GenSecurity(m.Security),
+	}
+	if m.Security != nil && len(m.Security) == 0 {
+		// Empty security list is omitted on marshaling, so it is set as a raw value,
+		// `[{}]` can't be used as it means optional authorization
+		op.MapOfAnything = map[string]any{"security": []any{}}
 	}
 
 	GenOperationRequest(&op, m)
@@ -123,31 +239,99 @@ func GenOperationRequest(op *oa.Operation, m parser.Method) {
 	for _, p := range m.Request.Headers {
 		op.Parameters = append(op.Parameters, GenHeader(p))
 	}
+	// Add cookie params
+	for _, p := range m.Request.Cookies {
+		op.Parameters = append(op.Parameters, GenCookie(p))
+	}
 
 	// Add body
 	if m.Request.Body != nil {
 		op.RequestBody = &oa.RequestBodyOrRef{
 			RequestBody: &oa.RequestBody{
-				Required: nilBool(true),
-				Content: map[string]oa.MediaType{
-					GenContentType(m.Request.Body): {
-						Schema: GenSchemaOrRef(*m.Request.Body),
-					},
-				},
+				Required: nilBool(!m.Request.Body.Optional),
+				Content:  GenContent(m.Request.Body),
 			},
 		}
 	}
 }
 
-func GenContentType(s *parser.Schema) string {
+// GenContent generates media type for each content type of the body
+func GenContent(s *parser.Schema) map[string]oa.MediaType {
+	content := map[string]oa.MediaType{}
+	// Example of the body is set for the whole media type
+	body := *s
+	body.Example = ""
+	for _, t := range GenContentTypes(s) {
+		media := oa.MediaType{
+			Schema:  GenSchemaOrRef(body),
+			Example: genExample(*s),
+		}
+		if t == parser.ContentTypeMultipart {
+			media.Encoding = GenEncoding(s)
+		}
+		content[t] = media
+	}
+	return content
+}
+
+// GenEncoding sets content types of multipart form parts
+func GenEncoding(s *parser.Schema) map[string]oa.Encoding {
+	encoding := map[string]oa.Encoding{}
+	for _, f := range s.Fields {
+		var contentType string
+		switch {
+		case f.Type == parser.TypeFile:
+			contentType = f.Format
+		case f.Type == parser.TypeObject, f.Type.IsRef():
+			contentType = parser.ContentTypeJSON
+		}
+		if contentType != "" {
+			encoding[f.Name] = oa.Encoding{ContentType: nilStr(contentType)}
+		}
+	}
+	if len(encoding) == 0 {
+		return nil
+	}
+	return encoding
+}
+
+// ogenEncoding keeps only JSON parts of the multipart encoding, content types of file parts are not supported by ogen
+func ogenEncoding(op *oa.Operation) {
+	if op.RequestBody == nil || op.RequestBody.RequestBody == nil {
+		return
+	}
+	content := op.RequestBody.RequestBody.Content
+	media, ok := content[parser.ContentTypeMultipart]
+	if !ok {
+		return
+	}
+	for name, e := range media.Encoding {
+		if e.ContentType == nil || *e.ContentType != parser.ContentTypeJSON {
+			delete(media.Encoding, name)
+		}
+	}
+	if len(media.Encoding) == 0 {
+		media.Encoding = nil
+	}
+	content[parser.ContentTypeMultipart] = media
+}
+
+func GenContentTypes(s *parser.Schema) []string {
+	if len(s.ContentTypes) != 0 {
+		return s.ContentTypes
+	}
 	switch s.Type {
 	case parser.TypeFile:
 		if s.Format == "" {
-			return "application/octet-stream"
+			return []string{"application/octet-stream"}
+		}
+		types := strings.Split(s.Format, ",")
+		for i := range types {
+			types[i] = strings.TrimSpace(types[i])
 		}
-		return s.Format
+		return types
 	default:
-		return "application/json"
+		return []string{parser.ContentTypeJSON}
 	}
 }
 
@@ -159,6 +343,7 @@ func GenParam(p parser.Schema) oa.ParameterOrRef {
 			Required:    nilBool(true),
 			Schema:      GenSchemaOrRef(p),
 			Description: nilStr(p.Description),
+			Deprecated:  nilTrue(p.Deprecated),
 		},
 	}
 }
@@ -171,6 +356,7 @@ func GenQuery(p parser.Schema) oa.ParameterOrRef {
 			Required:    nilBool(!p.Optional),
 			Schema:      GenSchemaOrRef(p),
 			Description: nilStr(p.Description),
+			Deprecated:  nilTrue(p.Deprecated),
 		},
 	}
 }
@@ -183,6 +369,20 @@ func GenHeader(p parser.Schema) oa.ParameterOrRef {
 			Required:    nilBool(!p.Optional),
 			Schema:      GenSchemaOrRef(p),
 			Description: nilStr(p.Description),
+			Deprecated:  nilTrue(p.Deprecated),
+		},
+	}
+}
+
+func GenCookie(p parser.Schema) oa.ParameterOrRef {
+	return oa.ParameterOrRef{
+		Parameter: &oa.Parameter{
+			Name:        p.Name,
+			In:          oa.ParameterInCookie,
+			Required:    nilBool(!p.Optional),
+			Schema:      GenSchemaOrRef(p),
+			Description: nilStr(p.Description),
+			Deprecated:  nilTrue(p.Deprecated),
 		},
 	}
 }
@@ -197,38 +397,48 @@ func GenOperationResponse(op *oa.Operation, m parser.Method) {
 
 	codes := map[string]oa.ResponseOrRef{}
 
-	// Add 200 OK response
+	// Add success response, 200 OK by default
+	code := m.Response.Code
+	if code == "" {
+		code = "200"
+	}
+	var success *oa.ResponseOrRef
 	if m.Response.Body != nil {
-		codes["200"] = *GenResponse(*m.Response.Body, "200")
+		success = GenResponse(*m.Response.Body, code)
+	} else if m.Response.Code != "" {
+		success = GenEmptyResponse(code)
 	}
 
-	// Add all error responses
-	for code, body := range m.Response.Errors {
+	// Add all other responses
+	for _, code := range m.Response.SortedCodes() {
+		body := m.Response.Codes[code]
+		if body == nil {
+			codes[code] = *GenEmptyResponse(code)
+			continue
+		}
 		codes[code] = *GenResponse(*body, code)
 	}
 
+	// Responses can't be empty, method without responses returns empty success response
+	if success == nil && len(codes) == 0 && resp.Default == nil {
+		success = GenEmptyResponse(code)
+	}
+
+	// Headers are sent with the success response only, they don't declare it
+	if success != nil {
+		GenResponseHeaders(success, m.Response.Headers)
+		codes[code] = *success
+	}
+
 	resp.MapOfResponseOrRefValues = codes
 	op.Responses = resp
 }
 
 func GenResponse(s parser.Schema, code string) *oa.ResponseOrRef {
-	var desc string
-	switch code {
-	case "":
-		desc = "Default response"
-	case "200":
-		desc = "Successful operation"
-	default:
-		desc = "Response on HTTP code " + code
-	}
 	res := &oa.ResponseOrRef{
 		Response: &oa.Response{
-			Description: desc,
-			Content: map[string]oa.MediaType{
-				GenContentType(&s): {
-					Schema: GenSchemaOrRef(s),
-				},
-			},
+			Description: ResponseDescription(code),
+			Content:     GenContent(&s),
 		},
 	}
 
@@ -251,41 +461,127 @@ func GenResponse(s parser.Schema, code string) *oa.ResponseOrRef {
 	return res
 }
 
+func GenResponseHeaders(r *oa.ResponseOrRef, headers []parser.Schema) {
+	if len(headers) == 0 {
+		return
+	}
+	if r.Response.Headers == nil {
+		r.Response.Headers = map[string]oa.HeaderOrRef{}
+	}
+	for _, h := range headers {
+		r.Response.Headers[h.Name] = oa.HeaderOrRef{
+			Header: &oa.Header{
+				Required:    nilBool(!h.Optional),
+				Schema:      GenSchemaOrRef(h),
+				Description: nilStr(h.Description),
+				Deprecated:  nilTrue(h.Deprecated),
+			},
+		}
+	}
+}
+
+func GenEmptyResponse(code string) *oa.ResponseOrRef {
+	return &oa.ResponseOrRef{
+		Response: &oa.Response{
+			Description: ResponseDescription(code),
+		},
+	}
+}
+
+// ResponseDescription returns description of the response with the status code, empty code is the default response
+func ResponseDescription(code string) string {
+	switch code {
+	case "":
+		return "Default response"
+	case "200":
+		return "Successful operation"
+	case "201":
+		return "Created"
+	case "202":
+		return "Accepted"
+	case "204":
+		return "No content"
+	default:
+		return "Response on HTTP code " + code
+	}
+}
+
 func GenSchemaOrRef(s parser.Schema) *oa.SchemaOrRef {
-	if !s.IsArray && s.Type.IsRef() {
-		return &oa.SchemaOrRef{
+	if !s.IsArray && !s.IsMap && s.Type.IsRef() {
+		ref := &oa.SchemaOrRef{
 			SchemaReference: &oa.SchemaReference{
 				Ref: "#/components/schemas/" + s.Type.Name(),
 			},
 		}
+		if !s.Nullable && !s.Deprecated && len(s.Fields) == 0 {
+			return ref
+		}
+		// Siblings of $ref are ignored, so nullable or deprecated reference is wrapped with allOf,
+		// enum references with default values are inlined by the parser.
+		// Fields added to the reference (merged common body) are the second part of allOf
+		allOf := []oa.SchemaOrRef{*ref}
+		if len(s.Fields) != 0 {
+			allOf = append(allOf, *GenSchemaOrRef(parser.Schema{Type: parser.TypeObject, Fields: s.Fields}))
+		}
+		return &oa.SchemaOrRef{
+			Schema: &oa.Schema{
+				AllOf:      allOf,
+				Nullable:   nilTrue(s.Nullable),
+				Deprecated: nilTrue(s.Deprecated),
+			},
+		}
 	}
 
 	schema := &oa.Schema{
 		Description: nilStr(s.Description),
-		Example:     nilAny(s.Example),
+		Example:     genExample(s),
+		Deprecated:  nilTrue(s.Deprecated),
 		Type:        nilType(s.Type),
 		Format:      nilFormat(s.Type),
+		Enum:        genEnum(s.Enum),
 	}
 
-	if s.IsArray {
-		t := oa.SchemaTypeArray
-		schema.Type = &t
-		schema.Format = nil
-		items := &oa.SchemaOrRef{}
-		if s.Type.IsRef() {
-			items.SchemaReference = &oa.SchemaReference{
-				Ref: "#/components/schemas/" + s.Type.Name(),
+	if s.IsArray || s.IsMap {
+		value := genValueSchemaOrRef(s)
+		if s.IsMap {
+			value = &oa.SchemaOrRef{
+				Schema: &oa.Schema{
+					Type: nilType(parser.TypeObject),
+					AdditionalProperties: &oa.SchemaAdditionalProperties{
+						SchemaOrRef: value,
+					},
+				},
 			}
+		}
+
+		if s.IsArray {
+			t := oa.SchemaTypeArray
+			schema.Type = &t
+			schema.Format = nil
+			schema.Enum = nil
+			schema.Items = value
+			genItemsConstraints(schema, s.Constraints)
 		} else {
-			items.Schema = &oa.Schema{
-				Type:   nilType(s.Type),
-				Format: nilFormat(s.Type),
-			}
+			value.Schema.Description = schema.Description
+			value.Schema.Example = schema.Example
+			value.Schema.Deprecated = schema.Deprecated
+			schema = value.Schema
+		}
+	} else {
+		genValueConstraints(schema, s.Constraints)
+		genUnion(schema, s)
+		schema.Default = genValue(s.Type, s.Enum, s.Default)
+	}
+
+	if s.Nullable {
+		schema.Nullable = nilBool(true)
+		// Enum restricts values even with nullable, so null is added explicitly
+		if schema.Enum != nil {
+			schema.Enum = append(schema.Enum, nil)
 		}
-		schema.Items = items
 	}
 
-	if s.Type == parser.TypeObject {
+	if s.Type == parser.TypeObject && !s.IsMap {
 		schema.Properties = map[string]oa.SchemaOrRef{}
 		for _, f := range s.Fields {
 			if !f.Optional {
@@ -300,6 +596,99 @@ func GenSchemaOrRef(s parser.Schema) *oa.SchemaOrRef {
 	return &oa.SchemaOrRef{Schema: schema}
 }
 
+// genValueSchemaOrRef generates schema of array items or map values
+func genValueSchemaOrRef(s parser.Schema) *oa.SchemaOrRef {
+	if s.Type.IsRef() {
+		return &oa.SchemaOrRef{
+			SchemaReference: &oa.SchemaReference{
+				Ref: "#/components/schemas/" + s.Type.Name(),
+			},
+		}
+	}
+
+	schema := &oa.Schema{
+		Type:   nilType(s.Type),
+		Format: nilFormat(s.Type),
+		Enum:   genEnum(s.Enum),
+	}
+	genValueConstraints(schema, s.Constraints)
+	genUnion(schema, s)
+
+	return &oa.SchemaOrRef{Schema: schema}
+}
+
+func genUnion(schema *oa.Schema, s parser.Schema) {
+	if !s.Type.IsUnion() {
+		return
+	}
+
+	variants := make([]oa.SchemaOrRef, 0, len(s.Variants))
+	for _, v := range s.Variants {
+		variants = append(variants, *genValueSchemaOrRef(parser.Schema{Type: v}))
+	}
+	if s.Type == parser.TypeOneOf {
+		schema.OneOf = variants
+	} else {
+		schema.AnyOf = variants
+	}
+
+	if s.Discriminator.Property == "" {
+		return
+	}
+	schema.Discriminator = &oa.Discriminator{
+		PropertyName: s.Discriminator.Property,
+	}
+	if len(s.Discriminator.Mapping) != 0 {
+		schema.Discriminator.Mapping = map[string]string{}
+		for _, m := range s.Discriminator.Mapping {
+			schema.Discriminator.Mapping[m.Value] = "#/components/schemas/" + m.Type.Name()
+		}
+	}
+}
+
+func genValueConstraints(schema *oa.Schema, c parser.Constraints) {
+	schema.Minimum = c.Min
+	schema.Maximum = c.Max
+	if c.ExclusiveMin {
+		schema.ExclusiveMinimum = nilBool(true)
+	}
+	if c.ExclusiveMax {
+		schema.ExclusiveMaximum = nilBool(true)
+	}
+	schema.MinLength = c.MinLength
+	schema.MaxLength = c.MaxLength
+	schema.Pattern = nilStr(c.Pattern)
+}
+
+func genItemsConstraints(schema *oa.Schema, c parser.Constraints) {
+	schema.MinItems = c.MinItems
+	schema.MaxItems = c.MaxItems
+	if c.UniqueItems {
+		schema.UniqueItems = nilBool(true)
+	}
+}
+
+// genValue converts value to the type, values are validated on parsing
+func genValue(t parser.Type, enum []string, val string) *any {
+	if val == "" {
+		return nil
+	}
+	v, err := parser.ParseValue(t, enum, val)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// genExample returns example parsed according to the type, examples are checked by the parser
+func genExample(s parser.Schema) *any {
+	v, err := parser.ParseExample(s)
+	if err != nil || v == nil {
+		return nil
+	}
+	return &v
+}
+
 func nilStr(s string) *string {
 	if s == "" {
 		return nil
@@ -314,6 +703,14 @@ func nilBool(b bool) *bool {
 	return &tmp
 }
 
+// nilTrue returns nil for false to omit flags which are false by default
+func nilTrue(b bool) *bool {
+	if !b {
+		return nil
+	}
+	return &b
+}
+
 func nilAny(s string) *any {
 	if s == "" {
 		return nil
@@ -322,11 +719,22 @@ func nilAny(s string) *any {
 	return &tmp
 }
 
+func genEnum(values []string) []any {
+	if len(values) == 0 {
+		return nil
+	}
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		res = append(res, v)
+	}
+	return res
+}
+
 func nilType(p parser.Type) *oa.SchemaType {
 	var t oa.SchemaType
 
 	switch p {
-	case parser.TypeAny:
+	case parser.TypeAny, parser.TypeOneOf, parser.TypeAnyOf:
 		return nil
 	case parser.TypeBool:
 		t = oa.SchemaTypeBoolean
@@ -334,6 +742,14 @@ func nilType(p parser.Type) *oa.SchemaType {
 		t = oa.SchemaTypeString
 	case parser.TypeUUID:
 		t = oa.SchemaTypeString
+	case parser.TypeDate, parser.TypeDateTime, parser.TypeTime, parser.TypeDuration:
+		t = oa.SchemaTypeString
+	case parser.TypeEmail, parser.TypeURI, parser.TypeIPv4, parser.TypeIPv6:
+		t = oa.SchemaTypeString
+	case parser.TypeByte, parser.TypeDecimal:
+		t = oa.SchemaTypeString
+	case parser.TypeEnum:
+		t = oa.SchemaTypeString
 	case parser.TypeFile:
 		t = oa.SchemaTypeString
 	case parser.TypeInt32:
@@ -365,6 +781,26 @@ func nilFormat(p parser.Type) *string {
 		f = "double"
 	case parser.TypeUUID:
 		f = "uuid"
+	case parser.TypeDate:
+		f = "date"
+	case parser.TypeDateTime:
+		f = "date-time"
+	case parser.TypeTime:
+		f = "time"
+	case parser.TypeDuration:
+		f = "duration"
+	case parser.TypeEmail:
+		f = "email"
+	case parser.TypeURI:
+		f = "uri"
+	case parser.TypeIPv4:
+		f = "ipv4"
+	case parser.TypeIPv6:
+		f = "ipv6"
+	case parser.TypeByte:
+		f = "byte"
+	case parser.TypeDecimal:
+		f = "decimal"
 	case parser.TypeFile:
 		f = "binary"
 	default:
@@ -374,7 +810,7 @@ func nilFormat(p parser.Type) *string {
 	return &f
 }
 
-func marshalYAML(s *oa.Spec, pathsOrder []string) ([]byte, error) {
+func marshalSpec(s *oa.Spec, pathsOrder []string, opts Options) ([]byte, error) {
 	jsonData, err := s.MarshalJSON()
 	if err != nil {
 		return nil, err
@@ -411,9 +847,59 @@ func marshalYAML(s *oa.Spec, pathsOrder []string) ([]byte, error) {
 		Value: yaml.MapSlice(orderedPaths),
 	}
 
+	if opts.Version == Version31 {
+		convert31(yaml.MapSlice(v))
+	}
+
+	if opts.Format == FormatJSON {
+		return marshalJSON(yaml.MapSlice(v))
+	}
 	return yaml.Marshal(yaml.MapSlice(v))
 }
 
+// marshalJSON writes indented JSON, keys of ordered maps keep their order and keys of other maps are sorted
+func marshalJSON(v yaml.MapSlice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v any) error {
+	items, ok := v.(yaml.MapSlice)
+	if !ok {
+		var val bytes.Buffer
+		enc := json.NewEncoder(&val)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		buf.Write(bytes.TrimSuffix(val.Bytes(), []byte("\n")))
+		return nil
+	}
+	buf.WriteByte('{')
+	for i, item := range items {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSON(buf, item.Key); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+		if err := writeJSON(buf, item.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
 type orderedMap []yaml.MapItem
 
 func (om *orderedMap) UnmarshalJSON(data []byte) error {
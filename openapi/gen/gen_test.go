@@ -0,0 +1,487 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Kegian/agen/openapi/parser"
+
+	oa "github.com/swaggest/openapi-go/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenOperationResponse(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    'DELETE /users/{user_id}':
+    'GET /users':
+      response:
+        404: empty
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := GenOperation(doc.API.Methods[0])
+	if _, ok := op.Responses.MapOfResponseOrRefValues["200"]; !ok || len(op.Responses.MapOfResponseOrRefValues) != 1 {
+		t.Errorf("method without responses: got %v, want empty 200 response", keys(op.Responses.MapOfResponseOrRefValues))
+	}
+
+	op = GenOperation(doc.API.Methods[1])
+	if got := keys(op.Responses.MapOfResponseOrRefValues); strings.Join(got, ",") != "404" {
+		t.Errorf("method with responses: got %v, want [404]", got)
+	}
+}
+
+func keys[T any](m map[string]T) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
+
+func TestGenerateSpecMethods(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    'GET /users/{id}':
+    'PUT /users/{id}':
+      request:
+        body: $User
+    'PATCH /users/{id}':
+      request:
+        body: $User?
+    'DELETE /users/{id}':
+    'HEAD /users/{id}':
+    'OPTIONS /users/{id}':
+schemas:
+  User:
+    name: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := GenerateSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res struct {
+		Paths map[string]map[string]struct {
+			OperationID string `yaml:"operationId"`
+			RequestBody *struct {
+				Required bool `yaml:"required"`
+			} `yaml:"requestBody"`
+		} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal([]byte(spec), &res); err != nil {
+		t.Fatal(err)
+	}
+	item := res.Paths["/users/{id}"]
+	if got := keys(item); len(got) != 6 {
+		t.Fatalf("got operations %v, want 6 operations", got)
+	}
+	for method, want := range map[string]string{"get": "GetUsers", "put": "PutUsers", "delete": "DeleteUsers", "head": "HeadUsers", "options": "OptionsUsers"} {
+		if got := item[method].OperationID; got != want {
+			t.Errorf("%s: got operation id %s, want %s", method, got, want)
+		}
+	}
+	if body := item["put"].RequestBody; body == nil || !body.Required {
+		t.Errorf("put: got body %+v, want required body", body)
+	}
+	if body := item["patch"].RequestBody; body == nil || body.Required {
+		t.Errorf("patch: got body %+v, want optional body", body)
+	}
+}
+
+func TestGenContentForms(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    'POST /login':
+      request:
+        form:
+          login: string
+          password: string
+    'POST /avatar':
+      request:
+        multipart:
+          avatar: file(image/png, image/jpeg)
+          thumbnails: file[]?
+          meta:
+            width: int32
+          title: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := GenOperation(doc.API.Methods[0]).RequestBody.RequestBody.Content
+	if media, ok := content[parser.ContentTypeForm]; !ok || len(content) != 1 || media.Encoding != nil {
+		t.Errorf("got form content %+v, want urlencoded form without encoding", content)
+	}
+
+	content = GenOperation(doc.API.Methods[1]).RequestBody.RequestBody.Content
+	media, ok := content[parser.ContentTypeMultipart]
+	if !ok || len(content) != 1 {
+		t.Fatalf("got content types %v, want multipart form", keys(content))
+	}
+	want := map[string]string{"avatar": "image/png, image/jpeg", "meta": parser.ContentTypeJSON}
+	if len(media.Encoding) != len(want) {
+		t.Errorf("got encoding of parts %v, want %v", keys(media.Encoding), want)
+	}
+	for part, contentType := range want {
+		if e := media.Encoding[part]; e.ContentType == nil || *e.ContentType != contentType {
+			t.Errorf("%s: got encoding %+v, want content type %s", part, e, contentType)
+		}
+	}
+	props := media.Schema.Schema.Properties
+	if f := props["avatar"].Schema; f == nil || *f.Type != "string" || *f.Format != "binary" {
+		t.Errorf("got file part %+v, want binary string", f)
+	}
+	if f := props["thumbnails"].Schema; f == nil || *f.Type != "array" {
+		t.Errorf("got files part %+v, want array", f)
+	}
+}
+
+func TestGenSecuritySchemes(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+settings:
+  security_schemes:
+    jwtAuth: bearer(JWT)
+    apiKey: header(X-API-Key)
+    oauth:
+      type: oauth2
+      flows:
+        client_credentials:
+          token_url: https://auth.example.com/token
+          scopes:
+            users:read: Read users
+  security:
+    - jwtAuth: []
+    - oauth: [users:read]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemes := map[string]*oa.SecurityScheme{}
+	for _, s := range doc.Settings.SecuritySchemes {
+		schemes[s.Name] = GenSecurityScheme(s)
+	}
+	if s := schemes["jwtAuth"].HTTPSecurityScheme; s == nil || s.Scheme != "bearer" || *s.BearerFormat != "JWT" {
+		t.Errorf("got jwtAuth scheme %+v, want bearer with JWT format", s)
+	}
+	if s := schemes["apiKey"].APIKeySecurityScheme; s == nil || s.Name != "X-API-Key" || s.In != oa.APIKeySecuritySchemeInHeader {
+		t.Errorf("got apiKey scheme %+v, want X-API-Key header", s)
+	}
+	if s := schemes["oauth"].OAuth2SecurityScheme; s == nil || s.Flows.ClientCredentials == nil ||
+		s.Flows.ClientCredentials.Scopes["users:read"] != "Read users" {
+		t.Errorf("got oauth scheme %+v, want client credentials flow with users:read scope", s)
+	}
+
+	security := GenSecurity(doc.Settings.Security)
+	if len(security) != 2 || len(security[1]["oauth"]) != 1 || security[1]["oauth"][0] != "users:read" {
+		t.Errorf("got security %v, want jwtAuth or oauth with users:read scope", security)
+	}
+}
+
+func TestGenOperationSecurity(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+settings:
+  security_schemes:
+    bearerAuth: bearer
+  security:
+    - bearerAuth: []
+api:
+  auth:
+    'POST /login':
+      security: none
+    'GET /me':
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := GenerateSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(spec, "      security: []\n") {
+		t.Errorf("public method should have empty security list:\n%s", spec)
+	}
+	if strings.Contains(spec, "- {}") {
+		t.Errorf("public method shouldn't have optional security:\n%s", spec)
+	}
+}
+
+func TestGenOperationSecurityOverrides(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+settings:
+  security_schemes:
+    bearerAuth: bearer
+    oauth:
+      type: oauth2
+      flows:
+        client_credentials:
+          token_url: https://auth.example.com/token
+          scopes:
+            users:write: Modify users
+  security:
+    - bearerAuth: []
+api:
+  auth:
+    _common:
+      security: none
+    'POST /login':
+    'POST /logout':
+      security:
+        - bearerAuth: []
+  admin:
+    'DELETE /users/{user_id}':
+      security:
+        - oauth: [users:write]
+  users:
+    'GET /me':
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"none", "bearerAuth", "oauth:users:write", ""} {
+		m := doc.API.Methods[i]
+		op := GenOperation(m)
+		got := ""
+		switch {
+		case op.MapOfAnything["security"] != nil:
+			got = "none"
+		case len(op.Security) == 1:
+			for name, scopes := range op.Security[0] {
+				got = strings.Join(append([]string{name}, scopes...), ":")
+			}
+		case len(op.Security) != 0:
+			got = fmt.Sprint(op.Security)
+		}
+		if got != want {
+			t.Errorf("%s %s: got security %q, want %q", m.Method, m.Path, got, want)
+		}
+	}
+}
+
+func TestGenSchemaMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		field parser.Schema
+		check func(s *oa.Schema) bool
+	}{
+		{
+			name:  "scalar values",
+			field: parser.Schema{Type: parser.TypeInt64, IsMap: true},
+			check: func(s *oa.Schema) bool {
+				v := s.AdditionalProperties.SchemaOrRef.Schema
+				return *s.Type == "object" && *v.Type == "integer" && *v.Format == "int64"
+			},
+		},
+		{
+			name:  "custom type values",
+			field: parser.Schema{Type: "$Counter", IsMap: true},
+			check: func(s *oa.Schema) bool {
+				return *s.Type == "object" && s.AdditionalProperties.SchemaOrRef.SchemaReference.Ref == "#/components/schemas/Counter"
+			},
+		},
+		{
+			name:  "array of maps",
+			field: parser.Schema{Type: parser.TypeString, IsMap: true, IsArray: true},
+			check: func(s *oa.Schema) bool {
+				items := s.Items.Schema
+				return *s.Type == "array" && *items.Type == "object" && *items.AdditionalProperties.SchemaOrRef.Schema.Type == "string"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := GenSchemaOrRef(tt.field)
+			if s.Schema == nil || !tt.check(s.Schema) {
+				t.Errorf("got schema %+v", s.Schema)
+			}
+		})
+	}
+}
+
+func TestGenSchemaNullableEnum(t *testing.T) {
+	s := GenSchemaOrRef(parser.Schema{Type: parser.TypeString, Enum: []string{"a", "b"}, Nullable: true})
+	enum := s.Schema.Enum
+	if len(enum) != 3 || enum[2] != nil {
+		t.Errorf("got enum %v, want [a b <nil>]", enum)
+	}
+
+	doc, err := parser.ParseDocument([]byte("schemas:\n  User:\n    status: enum(a,b)!null\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := GenerateSpecWithOptions(doc, Options{Version: Version31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(spec, "- null\n") != 1 || !strings.Contains(spec, "- \"null\"\n") {
+		t.Errorf("3.1 nullable enum should have a single null value and null type:\n%s", spec)
+	}
+}
+
+func TestGenOperationSuccessCodes(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    'POST /users':
+      response:
+        201: $User
+        409: $Error
+    'DELETE /users/{user_id}':
+      response:
+        204: empty
+        404: empty
+schemas:
+  User:
+    name: string
+  Error:
+    message: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"201,409", "204,404"} {
+		m := doc.API.Methods[i]
+		codes := GenOperation(m).Responses.MapOfResponseOrRefValues
+		got := keys(codes)
+		sort.Strings(got)
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s %s: got responses %v, want %s", m.Method, m.Path, got, want)
+		}
+	}
+
+	codes := GenOperation(doc.API.Methods[1]).Responses.MapOfResponseOrRefValues
+	if r := codes["204"].Response; r == nil || r.Content != nil || r.Description != "No content" {
+		t.Errorf("got 204 response %+v, want no content", r)
+	}
+}
+
+func TestGenResponseHeaders(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  _common:
+    response:
+      headers:
+        X-Request-Id: string
+  users:
+    'GET /users':
+      response:
+        body: string
+    'DELETE /users/{id}':
+      response:
+        404: empty
+    'POST /users':
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"200", "404", "200"} {
+		m := doc.API.Methods[i]
+		codes := GenOperation(m).Responses.MapOfResponseOrRefValues
+		if got := strings.Join(keys(codes), ","); got != want {
+			t.Errorf("%s %s: got responses %s, want %s", m.Method, m.Path, got, want)
+			continue
+		}
+		headers := codes[want].Response.Headers
+		if hasHeader := headers["X-Request-Id"].Header != nil; hasHeader != (want == "200") {
+			t.Errorf("%s %s: got headers %v", m.Method, m.Path, headers)
+		}
+	}
+}
+
+func TestGenCookies(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    'GET /me':
+      request:
+        cookies:
+          session: string
+          theme: string?
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := GenOperation(doc.API.Methods[0]).Parameters
+	if len(params) != 2 {
+		t.Fatalf("got %d parameters, want 2", len(params))
+	}
+	for i, required := range []bool{true, false} {
+		p := params[i].Parameter
+		if p.In != oa.ParameterInCookie || *p.Required != required {
+			t.Errorf("got parameter %s in %s required %v, want cookie required %v", p.Name, p.In, *p.Required, required)
+		}
+	}
+}
+
+func TestGenContentTypes(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  reports:
+    'POST /reports':
+      request:
+        body(xml): $Report
+      response:
+        body(json,csv): $Report
+        404(text): string
+    'GET /events':
+      response:
+        body(ndjson): $Report
+schemas:
+  Report:
+    name: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := GenOperation(doc.API.Methods[0])
+	if got := keys(op.RequestBody.RequestBody.Content); strings.Join(got, ",") != "application/xml" {
+		t.Errorf("got request content types %v, want application/xml", got)
+	}
+	codes := op.Responses.MapOfResponseOrRefValues
+	got := keys(codes["200"].Response.Content)
+	sort.Strings(got)
+	if strings.Join(got, ",") != "application/json,text/csv" {
+		t.Errorf("got response content types %v, want application/json and text/csv", got)
+	}
+	if got := keys(codes["404"].Response.Content); strings.Join(got, ",") != "text/plain" {
+		t.Errorf("got 404 content types %v, want text/plain", got)
+	}
+
+	op = GenOperation(doc.API.Methods[1])
+	if got := keys(op.Responses.MapOfResponseOrRefValues["200"].Response.Content); strings.Join(got, ",") != "application/x-ndjson" {
+		t.Errorf("got content types %v, want application/x-ndjson", got)
+	}
+}
+
+func TestGenSchemaOrRefFields(t *testing.T) {
+	s := GenSchemaOrRef(parser.Schema{
+		Type:   "$CreateUser",
+		Fields: []parser.Schema{{Name: "client_info", Type: parser.TypeString}},
+	})
+	if s.Schema == nil || len(s.Schema.AllOf) != 2 {
+		t.Fatalf("reference with fields should be allOf of the reference and the object, got %+v", s)
+	}
+	if ref := s.Schema.AllOf[0].SchemaReference; ref == nil || ref.Ref != "#/components/schemas/CreateUser" {
+		t.Errorf("got first part %+v, want reference", s.Schema.AllOf[0])
+	}
+	if _, ok := s.Schema.AllOf[1].Schema.Properties["client_info"]; !ok {
+		t.Errorf("got second part %+v, want object with client_info", s.Schema.AllOf[1].Schema)
+	}
+}
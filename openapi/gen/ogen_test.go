@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kegian/agen/openapi/parser"
+
+	"github.com/ogen-go/ogen"
+	ogengen "github.com/ogen-go/ogen/gen"
+)
+
+// generateOgen generates the spec of the document and runs ogen generator over it, files are not written
+func generateOgen(t *testing.T, data string, opts Options) (string, error) {
+	t.Helper()
+	doc, err := parser.ParseDocument([]byte("settings:\n  url: /api\n  title: Test\n  version: 1.0.0\n" + data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := GenerateSpecWithOptions(doc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ogen.Parse([]byte(spec))
+	if err != nil {
+		return spec, err
+	}
+	_, err = ogengen.NewGenerator(parsed, ogengen.Options{})
+	return spec, err
+}
+
+func TestOgenForms(t *testing.T) {
+	const forms = `
+api:
+  images:
+    'POST /login':
+      request:
+        form:
+          login: string
+          password: string
+    'POST /images':
+      request:
+        multipart:
+          image: file(image/png,image/jpeg)
+          thumbnails: file[]?
+          meta: $ImageMeta
+          size:
+            width: int32
+          title: string
+schemas:
+  ImageMeta:
+    tags: string[]
+`
+	spec, err := generateOgen(t, forms, Options{Ogen: true})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, spec)
+	}
+	if strings.Contains(spec, "image/png") || strings.Count(spec, "contentType: application/json") != 2 {
+		t.Errorf("only JSON parts should have content type for ogen:\n%s", spec)
+	}
+
+	spec, err = generateOgen(t, forms, Options{})
+	if err == nil || !strings.Contains(spec, "contentType: image/png,image/jpeg") {
+		t.Errorf("content types of file parts should be declared in the spec and rejected by ogen, got %v:\n%s", err, spec)
+	}
+}
+
+func TestOgenUnions(t *testing.T) {
+	const unions = `
+api:
+  events:
+    'GET /events':
+      response:
+        body: $Event
+    'GET /values':
+      response:
+        body: $Value
+schemas:
+  Event: oneOf[type](created=$UserCreated,deleted=$UserDeleted)
+  Value: anyOf(string,int64)
+  UserCreated:
+    type: string
+    name: string
+  UserDeleted:
+    type: string
+    user_id: int64
+`
+	if spec, err := generateOgen(t, unions, Options{Ogen: true}); err != nil {
+		t.Fatalf("%v\n%s", err, spec)
+	}
+
+	// anyOf of objects is supported by the spec only
+	const objects = `
+api:
+  channels:
+    'GET /channels':
+      response:
+        body: $Channel
+schemas:
+  Channel: anyOf($Email,$Sms)
+  Email:
+    address: string
+  Sms:
+    phone: string
+`
+	if _, err := generateOgen(t, objects, Options{Ogen: true}); err == nil || !strings.Contains(err.Error(), "anyOf") {
+		t.Errorf("got error %v, want anyOf error of ogen", err)
+	}
+}
+
+func TestOgenDefaults(t *testing.T) {
+	const defaults = `
+api:
+  users:
+    'GET /users':
+      request:
+        query:
+          limit: int32{min=1}? = 20
+          status: $Status? = active
+      response:
+        body: $User[]
+schemas:
+  Status: enum(active,blocked)
+  User:
+    name: string? = anonymous
+    status: $Status? = active
+`
+	spec, err := generateOgen(t, defaults, Options{Ogen: true})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, spec)
+	}
+	if strings.Count(spec, "default: active") != 2 || strings.Contains(spec, "allOf") {
+		t.Errorf("enum references with defaults should be inlined:\n%s", spec)
+	}
+}
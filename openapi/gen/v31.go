@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// convert31 converts generated OpenAPI 3.0 document to OpenAPI 3.1 semantics:
+// nullable values become type arrays or unions with null, examples become lists
+// and exclusive bounds become numbers
+func convert31(doc yaml.MapSlice) {
+	for i, item := range doc {
+		switch item.Key {
+		case "openapi":
+			doc[i].Value = "3.1.0"
+		case "components":
+			components, _ := item.Value.(map[string]any)
+			schemas, _ := components["schemas"].(map[string]any)
+			for _, s := range schemas {
+				convertSchema31(s)
+			}
+		default:
+			walk31(item.Value)
+		}
+	}
+}
+
+// walk31 converts schemas of parameters, headers and media types
+func walk31(v any) {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			walk31(item.Value)
+		}
+	case map[string]any:
+		for k, val := range v {
+			if k == "schema" {
+				convertSchema31(val)
+				continue
+			}
+			walk31(val)
+		}
+	case []any:
+		for _, val := range v {
+			walk31(val)
+		}
+	}
+}
+
+func convertSchema31(v any) {
+	s, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if nullable, _ := s["nullable"].(bool); nullable {
+		setNullable31(s)
+	}
+	delete(s, "nullable")
+
+	if example, ok := s["example"]; ok {
+		s["examples"] = []any{example}
+		delete(s, "example")
+	}
+
+	for key, exclusiveKey := range map[string]string{"minimum": "exclusiveMinimum", "maximum": "exclusiveMaximum"} {
+		if exclusive, ok := s[exclusiveKey].(bool); ok {
+			delete(s, exclusiveKey)
+			if exclusive {
+				s[exclusiveKey] = s[key]
+				delete(s, key)
+			}
+		}
+	}
+
+	if props, ok := s["properties"].(map[string]any); ok {
+		for _, p := range props {
+			convertSchema31(p)
+		}
+	}
+	convertSchema31(s["items"])
+	convertSchema31(s["additionalProperties"])
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		variants, _ := s[key].([]any)
+		for _, variant := range variants {
+			convertSchema31(variant)
+		}
+	}
+}
+
+// setNullable31 adds null to the type of the schema, unions get the null variant
+// and the reference wrapped with allOf becomes a union with null
+func setNullable31(s map[string]any) {
+	if t, ok := s["type"].(string); ok {
+		s["type"] = []any{t, "null"}
+		if enum, ok := s["enum"].([]any); ok && !hasNull(enum) {
+			s["enum"] = append(enum, nil)
+		}
+		return
+	}
+
+	null := map[string]any{"type": "null"}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants, ok := s[key].([]any); ok {
+			s[key] = append(variants, null)
+			return
+		}
+	}
+	if refs, ok := s["allOf"].([]any); ok && len(refs) == 1 {
+		s["anyOf"] = append(refs, null)
+		delete(s, "allOf")
+	}
+	// Schema without type already allows null
+}
+
+func hasNull(values []any) bool {
+	for _, v := range values {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
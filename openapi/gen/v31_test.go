@@ -0,0 +1,102 @@
+package gen
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Kegian/agen/openapi/parser"
+)
+
+func TestConvert31(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    GET /users:
+      request:
+        query:
+          limit: int32!null{exclusive_min=0}? # Limit (10)
+      response:
+        body: $User[]
+schemas:
+  User:
+    name: string!null # Name (John)
+    age: int32{exclusive_max=150,min=0}
+    status: $Status!null
+    value: oneOf(string,int64)!null
+    data: any!null
+  Status: enum(active,blocked)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := GenerateSpecWithOptions(doc, Options{Version: Version31, Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Openapi    string
+		Paths      map[string]map[string]map[string]any
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Openapi != "3.1.0" {
+		t.Errorf("got version %s, want 3.1.0", spec.Openapi)
+	}
+
+	props := spec.Components.Schemas["User"].Properties
+	tests := map[string]map[string]any{
+		"name":   {"description": "Name", "type": []any{"string", "null"}, "examples": []any{"John"}},
+		"age":    {"type": "integer", "format": "int32", "minimum": 0.0, "exclusiveMaximum": 150.0},
+		"status": {"anyOf": []any{map[string]any{"$ref": "#/components/schemas/Status"}, map[string]any{"type": "null"}}},
+		"value":  {"oneOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer", "format": "int64"}, map[string]any{"type": "null"}}},
+		"data":   {},
+	}
+	for name, want := range tests {
+		if got := props[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	// Schemas of parameters are converted too
+	params := spec.Paths["/users"]["get"]["parameters"].([]any)
+	schema := params[0].(map[string]any)["schema"]
+	want := map[string]any{"description": "Limit", "type": []any{"integer", "null"}, "format": "int32", "exclusiveMinimum": 0.0, "examples": []any{10.0}}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("parameter: got %v, want %v", schema, want)
+	}
+}
+
+func TestGenerateSpecFormats(t *testing.T) {
+	doc, err := parser.ParseDocument([]byte(`
+api:
+  users:
+    POST /users:
+    GET /users:
+  images:
+    GET /images:
+    DELETE /avatars:
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := GenerateSpecWithOptions(doc, Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paths keep the order of declaration in JSON
+	users, images, avatars := strings.Index(data, `"/users"`), strings.Index(data, `"/images"`), strings.Index(data, `"/avatars"`)
+	if users < 0 || users > images || images > avatars {
+		t.Errorf("got paths order %d, %d, %d, want order of declaration", users, images, avatars)
+	}
+	if !json.Valid([]byte(data)) {
+		t.Errorf("got invalid JSON:\n%s", data)
+	}
+}
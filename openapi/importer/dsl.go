@@ -0,0 +1,452 @@
+package importer
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/Kegian/agen/openapi/formatter"
+	"github.com/Kegian/agen/openapi/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GenDSL writes imported document in agen format
+func GenDSL(res Result) (string, error) {
+	doc := res.Document
+	root := mapping()
+
+	settings := mapping()
+	addScalar(settings, "title", doc.Settings.Title)
+	addScalar(settings, "version", doc.Settings.Version)
+	addScalar(settings, "url", doc.Settings.URL)
+	if len(doc.Settings.SecuritySchemes) != 0 {
+		schemes := mapping()
+		for _, s := range doc.Settings.SecuritySchemes {
+			k, v := securitySchemeNode(s)
+			schemes.Content = append(schemes.Content, k, v)
+		}
+		add(settings, "security_schemes", schemes)
+	}
+	if doc.Settings.Security != nil {
+		add(settings, "security", securityNode(doc.Settings.Security))
+	}
+	add(root, "settings", settings)
+
+	api := mapping()
+	if res.Common != nil {
+		add(api, "_common", methodNode(*res.Common))
+	}
+	for _, t := range doc.API.Tags {
+		methods := mapping()
+		for _, m := range doc.API.Methods {
+			if m.Tag != t.Name {
+				continue
+			}
+			key := scalar(m.Method + " " + m.Path)
+			key.Style = yaml.SingleQuotedStyle
+			value := methodNode(m)
+			setComment(key, value, m.Description, "")
+			methods.Content = append(methods.Content, key, value)
+		}
+		if len(methods.Content) == 0 {
+			continue
+		}
+		key := scalar(t.Name)
+		setComment(key, methods, t.Description, "")
+		api.Content = append(api.Content, key, methods)
+	}
+	if len(api.Content) != 0 {
+		add(root, "api", api)
+	}
+
+	if len(doc.Schemas) != 0 {
+		schemas := mapping()
+		for _, s := range doc.Schemas {
+			k, v := schemaNode(s)
+			schemas.Content = append(schemas.Content, k, v)
+		}
+		add(root, "schemas", schemas)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	// Empty lines and alignment of comments are the same as in formatted files
+	out, err := formatter.Format(buf.Bytes(), formatter.Options{})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func securitySchemeNode(s parser.SecurityScheme) (*yaml.Node, *yaml.Node) {
+	key := scalar(s.Name)
+	var value *yaml.Node
+	switch s.Type {
+	case parser.SecurityTypeHTTP:
+		if s.Scheme == "bearer" || s.Scheme == "basic" {
+			val := s.Scheme
+			if s.BearerFormat != "" {
+				val += "(" + s.BearerFormat + ")"
+			}
+			value = scalar(val)
+			break
+		}
+		value = mapping()
+		addScalar(value, "type", string(s.Type))
+		addScalar(value, "scheme", s.Scheme)
+	case parser.SecurityTypeAPIKey:
+		value = scalar(s.In + "(" + s.Param + ")")
+	default:
+		value = mapping()
+		addScalar(value, "type", string(s.Type))
+		flows := mapping()
+		for _, f := range s.Flows {
+			flow := mapping()
+			addScalar(flow, "authorization_url", f.AuthorizationURL)
+			addScalar(flow, "token_url", f.TokenURL)
+			addScalar(flow, "refresh_url", f.RefreshURL)
+			scopes := mapping()
+			for _, sc := range f.Scopes {
+				add(scopes, sc.Name, scalar(sc.Description))
+			}
+			add(flow, "scopes", scopes)
+			add(flows, string(f.Type), flow)
+		}
+		add(value, "flows", flows)
+	}
+	if value.Kind == yaml.MappingNode {
+		addScalar(value, "description", s.Description)
+	} else {
+		setComment(key, value, s.Description, "")
+	}
+	return key, value
+}
+
+func securityNode(reqs []parser.SecurityRequirement) *yaml.Node {
+	if len(reqs) == 0 {
+		return scalar("none")
+	}
+	res := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, r := range reqs {
+		req := mapping()
+		req.Style = yaml.FlowStyle
+		for _, name := range r.Names() {
+			scopes := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, s := range r[name] {
+				scopes.Content = append(scopes.Content, scalar(s))
+			}
+			add(req, name, scopes)
+		}
+		res.Content = append(res.Content, req)
+	}
+	return res
+}
+
+func methodNode(m parser.Method) *yaml.Node {
+	res := mapping()
+	addScalar(res, "name", m.Name)
+	if m.Deprecated {
+		add(res, "deprecated", scalar("true"))
+	}
+	if m.Security != nil {
+		add(res, "security", securityNode(m.Security))
+	}
+
+	req := mapping()
+	addFields(req, "params", pathParams(m.Request.Params))
+	addFields(req, "query", m.Request.Query)
+	addFields(req, "headers", m.Request.Headers)
+	addFields(req, "cookies", m.Request.Cookies)
+	if b := m.Request.Body; b != nil {
+		key := "body"
+		switch {
+		case len(b.ContentTypes) == 1 && b.ContentTypes[0] == parser.ContentTypeForm:
+			key = "form"
+		case len(b.ContentTypes) == 1 && b.ContentTypes[0] == parser.ContentTypeMultipart:
+			key = "multipart"
+		default:
+			key += contentTypesSuffix(b.ContentTypes)
+		}
+		s := *b
+		s.Name = key
+		k, v := schemaNode(s)
+		req.Content = append(req.Content, k, v)
+	}
+	if len(req.Content) != 0 {
+		add(res, "request", req)
+	}
+
+	resp := mapping()
+	addFields(resp, "headers", m.Response.Headers)
+	if m.Response.Body != nil || m.Response.Code != "" && m.Response.Code != "200" {
+		key := m.Response.Code
+		if key == "" || key == "200" {
+			key = "body"
+		}
+		addBody(resp, key, m.Response.Body)
+	}
+	for _, code := range m.Response.SortedCodes() {
+		addBody(resp, code, m.Response.Codes[code])
+	}
+	if m.Response.Default != nil {
+		addBody(resp, "default", m.Response.Default)
+	}
+	if len(resp.Content) != 0 {
+		add(res, "response", resp)
+	}
+
+	if len(res.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	}
+	return res
+}
+
+// pathParams skips string params without description, they are declared by the path
+func pathParams(params []parser.Schema) []parser.Schema {
+	res := []parser.Schema{}
+	for _, p := range params {
+		if p.Type == parser.TypeString && p.Description == "" && p.Example == "" && !p.Deprecated && p.Constraints.IsEmpty() {
+			continue
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+func addFields(n *yaml.Node, key string, fields []parser.Schema) {
+	if len(fields) == 0 {
+		return
+	}
+	k, v := schemaNode(parser.Schema{Name: key, Type: parser.TypeObject, Fields: fields})
+	n.Content = append(n.Content, k, v)
+}
+
+// addBody adds response body, nil body is an empty response
+func addBody(n *yaml.Node, key string, body *parser.Schema) {
+	if body == nil {
+		add(n, key, scalar("empty"))
+		return
+	}
+	s := *body
+	s.Name = key + contentTypesSuffix(body.ContentTypes)
+	k, v := schemaNode(s)
+	n.Content = append(n.Content, k, v)
+}
+
+var contentTypeAliases = map[string]string{
+	parser.ContentTypeJSON:        "json",
+	parser.ContentTypeText:        "text",
+	parser.ContentTypeCSV:         "csv",
+	parser.ContentTypeXML:         "xml",
+	parser.ContentTypeNDJSON:      "ndjson",
+	parser.ContentTypeEventStream: "sse",
+}
+
+func contentTypesSuffix(types []string) string {
+	if len(types) == 0 {
+		return ""
+	}
+	res := make([]string, 0, len(types))
+	for _, t := range types {
+		if alias, ok := contentTypeAliases[t]; ok {
+			t = alias
+		}
+		res = append(res, t)
+	}
+	return "(" + strings.Join(res, ",") + ")"
+}
+
+// schemaNode returns key and value of the schema, objects are declared inline,
+// long form is used for deprecated values and examples which can't be set in comments
+func schemaNode(s parser.Schema) (*yaml.Node, *yaml.Node) {
+	name := s.Name
+	if len(s.Embeds) != 0 {
+		embeds := make([]string, 0, len(s.Embeds))
+		for _, e := range s.Embeds {
+			embeds = append(embeds, string(e))
+		}
+		name += "<" + strings.Join(embeds, ",") + ">"
+	}
+	key := scalar(name)
+
+	var value *yaml.Node
+	if isInlineObject(s) || s.Type == parser.TypeObject && len(s.Fields) == 0 && len(s.Embeds) != 0 {
+		value = mapping()
+		if len(s.Fields) == 0 {
+			value.Style = yaml.FlowStyle
+		}
+		for _, f := range s.Fields {
+			k, v := schemaNode(f)
+			value.Content = append(value.Content, k, v)
+		}
+	} else {
+		value = scalar(typeString(s))
+	}
+
+	example := s.Example
+	if s.Deprecated || strings.ContainsAny(example, "()\n") {
+		long := mapping()
+		add(long, parser.LongFormType, value)
+		if s.Description != "" {
+			addScalar(long, "description", s.Description)
+		}
+		if example != "" {
+			addScalar(long, "example", example)
+		}
+		if s.Deprecated {
+			add(long, "deprecated", scalar("true"))
+		}
+		return key, long
+	}
+
+	setComment(key, value, s.Description, example)
+	return key, value
+}
+
+// typeString returns scalar declaration of the type, e.g. `int32[]{max_items=10}!null? = 1`
+func typeString(s parser.Schema) string {
+	var base string
+	switch {
+	case s.Type == parser.TypeEnum:
+		base = "enum(" + strings.Join(s.Enum, ",") + ")"
+	case s.Type.IsUnion():
+		base = string(s.Type)
+		if s.Discriminator.Property != "" {
+			base += "[" + s.Discriminator.Property + "]"
+		}
+		mapping := map[parser.Type]string{}
+		for _, m := range s.Discriminator.Mapping {
+			mapping[m.Type] = m.Value
+		}
+		variants := make([]string, 0, len(s.Variants))
+		for _, v := range s.Variants {
+			if value, ok := mapping[v]; ok {
+				variants = append(variants, value+"="+string(v))
+			} else {
+				variants = append(variants, string(v))
+			}
+		}
+		base += "(" + strings.Join(variants, ",") + ")"
+	case s.Type == parser.TypeFile && s.Format != "":
+		base = "file(" + s.Format + ")"
+	default:
+		base = string(s.Type)
+	}
+
+	if s.IsMap {
+		base = "map[" + base + "]"
+	}
+	if s.IsArray {
+		base += "[]"
+	}
+	if c := constraintsString(s.Constraints); c != "" {
+		base += "{" + c + "}"
+	}
+	if s.Nullable {
+		base += "!null"
+	}
+	if s.Optional {
+		base += "?"
+	}
+	if s.Default != "" {
+		base += " = " + s.Default
+	}
+	return base
+}
+
+func constraintsString(c parser.Constraints) string {
+	items := []string{}
+	float := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if c.Min != nil {
+		key := "min"
+		if c.ExclusiveMin {
+			key = "exclusive_min"
+		}
+		items = append(items, key+"="+float(*c.Min))
+	}
+	if c.Max != nil {
+		key := "max"
+		if c.ExclusiveMax {
+			key = "exclusive_max"
+		}
+		items = append(items, key+"="+float(*c.Max))
+	}
+	if c.MinLength != nil {
+		items = append(items, "min_length="+strconv.FormatInt(*c.MinLength, 10))
+	}
+	if c.MaxLength != nil {
+		items = append(items, "max_length="+strconv.FormatInt(*c.MaxLength, 10))
+	}
+	if c.Pattern != "" {
+		quote := `"`
+		if strings.Contains(c.Pattern, quote) {
+			quote = "'"
+		}
+		items = append(items, "pattern="+quote+c.Pattern+quote)
+	}
+	if c.MinItems != nil {
+		items = append(items, "min_items="+strconv.FormatInt(*c.MinItems, 10))
+	}
+	if c.MaxItems != nil {
+		items = append(items, "max_items="+strconv.FormatInt(*c.MaxItems, 10))
+	}
+	if c.UniqueItems {
+		items = append(items, "unique_items")
+	}
+	return strings.Join(items, ",")
+}
+
+// setComment sets description and example in comments, multi-line descriptions and descriptions
+// which can be read as an example are set in the head comment
+func setComment(key, value *yaml.Node, description, example string) {
+	inHead := strings.Contains(description, "\n") || example == "" && strings.HasSuffix(description, ")")
+	line := description
+	if inHead {
+		lines := strings.Split(description, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimSpace("# " + l)
+		}
+		key.HeadComment = strings.Join(lines, "\n")
+		line = ""
+	}
+	if example != "" {
+		line = strings.TrimSpace(line + " (" + example + ")")
+	}
+	if line == "" {
+		return
+	}
+	if value.Kind == yaml.ScalarNode {
+		value.LineComment = "# " + line
+	} else {
+		key.LineComment = "# " + line
+	}
+}
+
+func mapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode}
+}
+
+func scalar(val string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: val}
+}
+
+func add(n *yaml.Node, key string, value *yaml.Node) {
+	n.Content = append(n.Content, scalar(key), value)
+}
+
+// addScalar adds non-empty value
+func addScalar(n *yaml.Node, key, value string) {
+	if value != "" {
+		add(n, key, scalar(value))
+	}
+}
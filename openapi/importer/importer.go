@@ -0,0 +1,1186 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Kegian/agen/openapi/gen"
+	"github.com/Kegian/agen/openapi/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CodeUnsupported is a code of warnings about constructs which can't be expressed in agen format
+const CodeUnsupported = "unsupported"
+
+// Result is an imported document, parts shared by all methods are extracted to the common section
+type Result struct {
+	Document parser.Document
+	Common   *parser.Method
+}
+
+// Import converts OpenAPI 3 document (YAML or JSON) into agen document,
+// constructs which can't be expressed in agen format are reported as warnings
+func Import(data []byte) (Result, parser.Diagnostics, error) {
+	var base yaml.Node
+	if err := yaml.Unmarshal(data, &base); err != nil {
+		return Result{}, nil, err
+	}
+	if base.Kind != yaml.DocumentNode || len(base.Content) != 1 || base.Content[0].Kind != yaml.MappingNode {
+		return Result{}, nil, parser.Err(&base, "should be OpenAPI document")
+	}
+	root := base.Content[0]
+	version := str(root, "openapi")
+	if !strings.HasPrefix(version, "3.") {
+		return Result{}, nil, parser.Err(root, "only OpenAPI 3 documents are supported")
+	}
+
+	im := &importer{
+		root:  root,
+		names: map[string]string{},
+		taken: map[string]struct{}{},
+	}
+	im.settings()
+	im.componentNames()
+	im.schemas()
+	im.paths()
+	im.fillNames()
+
+	res := Result{Document: im.doc}
+	res.Common = extractCommon(res.Document.API.Methods)
+	return res, im.diags, nil
+}
+
+type importer struct {
+	root    *yaml.Node
+	doc     parser.Document
+	diags   parser.Diagnostics
+	names   map[string]string   // Names of component schemas in agen format
+	taken   map[string]struct{} // Names of declared schemas
+	hoisted []parser.Schema     // Schemas declared from inline values, see hoist
+	schemes map[string]struct{}
+}
+
+func (im *importer) warn(n *yaml.Node, msg string) {
+	d := parser.Diagnostic{Severity: parser.SeverityWarning, Code: CodeUnsupported, Message: msg}
+	if n != nil {
+		d.Line = n.Line
+		d.Column = n.Column
+	}
+	im.diags = append(im.diags, d)
+}
+
+func (im *importer) settings() {
+	s := &im.doc.Settings
+	info := get(im.root, "info")
+	s.Title = str(info, "title")
+	s.Version = str(info, "version")
+	if servers := get(im.root, "servers"); servers != nil && servers.Kind == yaml.SequenceNode && len(servers.Content) != 0 {
+		s.URL = str(servers.Content[0], "url")
+		if len(servers.Content) > 1 {
+			im.warn(servers, "only the first server is imported")
+		}
+	}
+	for _, key := range []string{"externalDocs", "webhooks", "jsonSchemaDialect"} {
+		if n := get(im.root, key); n != nil {
+			im.warn(n, "`"+key+"` is not supported")
+		}
+	}
+
+	im.schemes = map[string]struct{}{}
+	for _, p := range pairs(get(get(im.root, "components"), "securitySchemes")) {
+		scheme, ok := im.securityScheme(p.Left.Value, im.resolve(p.Right))
+		if !ok {
+			continue
+		}
+		im.schemes[scheme.Name] = struct{}{}
+		s.SecuritySchemes = append(s.SecuritySchemes, scheme)
+	}
+
+	if n := get(im.root, "security"); n != nil {
+		s.Security = im.security(n)
+	} else if len(s.SecuritySchemes) == 0 {
+		// Default optional bearer authorization of agen is disabled
+		s.Security = []parser.SecurityRequirement{}
+	}
+
+	for _, t := range seq(get(im.root, "tags")) {
+		im.addTag(str(t, "name"), str(t, "description"))
+	}
+}
+
+var oauthFlows = map[string]parser.OAuthFlowType{
+	"implicit":          parser.OAuthFlowImplicit,
+	"password":          parser.OAuthFlowPassword,
+	"clientCredentials": parser.OAuthFlowClientCredentials,
+	"authorizationCode": parser.OAuthFlowAuthorizationCode,
+}
+
+func (im *importer) securityScheme(name string, n *yaml.Node) (parser.SecurityScheme, bool) {
+	s := parser.SecurityScheme{Name: name, Description: str(n, "description")}
+	switch str(n, "type") {
+	case "http":
+		s.Type = parser.SecurityTypeHTTP
+		s.Scheme = strings.ToLower(str(n, "scheme"))
+		s.BearerFormat = str(n, "bearerFormat")
+	case "apiKey":
+		s.Type = parser.SecurityTypeAPIKey
+		s.In = str(n, "in")
+		s.Param = str(n, "name")
+	case "oauth2":
+		s.Type = parser.SecurityTypeOAuth2
+		for _, p := range pairs(get(n, "flows")) {
+			typ, ok := oauthFlows[p.Left.Value]
+			if !ok {
+				im.warn(p.Left, "unknown oauth2 flow `"+p.Left.Value+"`")
+				continue
+			}
+			flow := parser.OAuthFlow{
+				Type:             typ,
+				AuthorizationURL: str(p.Right, "authorizationUrl"),
+				TokenURL:         str(p.Right, "tokenUrl"),
+				RefreshURL:       str(p.Right, "refreshUrl"),
+			}
+			for _, sc := range pairs(get(p.Right, "scopes")) {
+				flow.Scopes = append(flow.Scopes, parser.OAuthScope{Name: sc.Left.Value, Description: sc.Right.Value})
+			}
+			s.Flows = append(s.Flows, flow)
+		}
+	default:
+		im.warn(n, fmt.Sprintf("security scheme `%s` of type `%s` is not supported", name, str(n, "type")))
+		return parser.SecurityScheme{}, false
+	}
+	return s, true
+}
+
+// security converts requirements, requirements with unsupported schemes are skipped
+func (im *importer) security(n *yaml.Node) []parser.SecurityRequirement {
+	res := []parser.SecurityRequirement{}
+	for _, item := range seq(n) {
+		req := parser.SecurityRequirement{}
+		supported := true
+		for _, p := range pairs(item) {
+			if _, ok := im.schemes[p.Left.Value]; !ok {
+				im.warn(p.Left, "security requirement `"+p.Left.Value+"` refers to unsupported scheme")
+				supported = false
+				break
+			}
+			scopes := []string{}
+			for _, s := range seq(p.Right) {
+				scopes = append(scopes, s.Value)
+			}
+			req[p.Left.Value] = scopes
+		}
+		if supported {
+			res = append(res, req)
+		}
+	}
+	return res
+}
+
+func (im *importer) addTag(name, description string) {
+	for i, t := range im.doc.API.Tags {
+		if t.Name == name {
+			if t.Description == "" {
+				im.doc.API.Tags[i].Description = description
+			}
+			return
+		}
+	}
+	im.doc.API.Tags = append(im.doc.API.Tags, parser.Tag{Name: name, Description: description})
+}
+
+var nameRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// componentNames reserves names of component schemas, names are converted to identifiers
+func (im *importer) componentNames() {
+	for _, p := range pairs(get(get(im.root, "components"), "schemas")) {
+		name := identifier(p.Left.Value)
+		if name != p.Left.Value {
+			im.warn(p.Left, fmt.Sprintf("schema `%s` is renamed to `%s`", p.Left.Value, name))
+		}
+		im.names[p.Left.Value] = im.reserve(name)
+	}
+}
+
+func identifier(val string) string {
+	parts := nameRe.Split(val, -1)
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	res := strings.Join(parts, "")
+	if res == "" || res[0] >= '0' && res[0] <= '9' {
+		res = "Schema" + res
+	}
+	return res
+}
+
+// reserve returns unique schema name based on the name
+func (im *importer) reserve(name string) string {
+	res := name
+	for i := 2; ; i++ {
+		if _, ok := im.taken[res]; !ok {
+			break
+		}
+		res = name + strconv.Itoa(i)
+	}
+	im.taken[res] = struct{}{}
+	return res
+}
+
+func (im *importer) schemas() {
+	for _, p := range pairs(get(get(im.root, "components"), "schemas")) {
+		name := im.names[p.Left.Value]
+		s := im.schema(p.Right, name)
+		s.Name = name
+		s.Optional = false
+		// Schema extending a single object is declared via embedding, it keeps the schema an object
+		if parts := seq(get(p.Right, "allOf")); len(parts) == 1 && s.Type.IsRef() && !s.IsArray && !s.IsMap && !s.Nullable {
+			if ref := im.resolve(parts[0]); get(ref, "properties") != nil || get(ref, "allOf") != nil {
+				s.Embeds = []parser.Type{s.Type}
+				s.Type = parser.TypeObject
+			}
+		}
+		if s.Default != "" {
+			im.warn(p.Right, "default value of schema `"+name+"` is allowed for fields only")
+			s.Default = ""
+		}
+		im.doc.Schemas = append(im.doc.Schemas, s)
+		im.flush()
+	}
+	for _, key := range []string{"examples", "links", "callbacks", "pathItems"} {
+		if n := get(get(im.root, "components"), key); n != nil {
+			im.warn(n, "components `"+key+"` are not supported")
+		}
+	}
+}
+
+// flush adds hoisted schemas after the schema they were declared in
+func (im *importer) flush() {
+	im.doc.Schemas = append(im.doc.Schemas, im.hoisted...)
+	im.hoisted = nil
+}
+
+// hoist declares the schema with the name based on the hint and returns its type,
+// it is used for values which can't be declared inline (e.g. arrays of objects)
+func (im *importer) hoist(s parser.Schema, hint string) parser.Type {
+	s.Name = im.reserve(identifier(hint))
+	s.Optional = false
+	s.Default = ""
+	s.ContentTypes = nil
+	im.hoisted = append(im.hoisted, s)
+	return parser.Type("$" + s.Name)
+}
+
+// isInlineObject reports if the schema is declared as object with fields
+func isInlineObject(s parser.Schema) bool {
+	return s.Type == parser.TypeObject && !s.IsArray && !s.IsMap && (len(s.Fields) != 0 || len(s.Embeds) != 0)
+}
+
+// named returns the schema declared inline if possible, otherwise the reference to the hoisted schema
+func (im *importer) named(s parser.Schema, hint string) parser.Schema {
+	if !isInlineObject(s) || !s.Optional && !s.Nullable && s.Default == "" {
+		return s
+	}
+	return parser.Schema{
+		Name:         s.Name,
+		Type:         im.hoist(s, hint),
+		Optional:     s.Optional,
+		Nullable:     s.Nullable,
+		Description:  s.Description,
+		Deprecated:   s.Deprecated,
+		ContentTypes: s.ContentTypes,
+	}
+}
+
+// field converts property of the object or parameter
+func (im *importer) field(name string, n *yaml.Node, required bool, hint string) parser.Schema {
+	s := im.schema(n, hint)
+	s.Name = name
+	s.Optional = !required
+	if s.Default != "" && !s.Optional {
+		im.warn(n, "default value of required field `"+name+"` is not supported")
+		s.Default = ""
+	}
+	return im.named(s, hint)
+}
+
+// schemaKeys are keys of a schema which are converted or ignored on purpose
+var schemaKeys = map[string]struct{}{
+	"$ref": {}, "type": {}, "format": {}, "nullable": {}, "description": {}, "example": {}, "examples": {},
+	"deprecated": {}, "default": {}, "enum": {}, "items": {}, "properties": {}, "required": {},
+	"additionalProperties": {}, "allOf": {}, "oneOf": {}, "anyOf": {}, "discriminator": {},
+	"minimum": {}, "maximum": {}, "exclusiveMinimum": {}, "exclusiveMaximum": {}, "minLength": {}, "maxLength": {},
+	"pattern": {}, "minItems": {}, "maxItems": {}, "uniqueItems": {}, "title": {},
+}
+
+var stringFormats = map[string]parser.Type{
+	"":          parser.TypeString,
+	"uuid":      parser.TypeUUID,
+	"date":      parser.TypeDate,
+	"date-time": parser.TypeDateTime,
+	"time":      parser.TypeTime,
+	"duration":  parser.TypeDuration,
+	"email":     parser.TypeEmail,
+	"uri":       parser.TypeURI,
+	"url":       parser.TypeURI,
+	"ipv4":      parser.TypeIPv4,
+	"ipv6":      parser.TypeIPv6,
+	"byte":      parser.TypeByte,
+	"decimal":   parser.TypeDecimal,
+	"binary":    parser.TypeFile,
+}
+
+// schema converts schema node, the hint is used for names of hoisted schemas
+func (im *importer) schema(n *yaml.Node, hint string) parser.Schema {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return parser.Schema{Type: parser.TypeAny}
+	}
+	if ref := str(n, "$ref"); ref != "" {
+		if strings.HasPrefix(ref, "#/components/schemas/") {
+			if agen, ok := im.names[unescape(strings.TrimPrefix(ref, "#/components/schemas/"))]; ok {
+				return parser.Schema{Type: parser.Type("$" + agen)}
+			}
+		}
+		target := im.resolve(n)
+		if target == n {
+			im.warn(n, "reference `"+ref+"` is not found")
+			return parser.Schema{Type: parser.TypeAny}
+		}
+		return im.schema(target, hint)
+	}
+
+	for _, p := range pairs(n) {
+		if _, ok := schemaKeys[p.Left.Value]; !ok && !strings.HasPrefix(p.Left.Value, "x-") {
+			im.warn(p.Left, "schema keyword `"+p.Left.Value+"` is not supported")
+		}
+	}
+
+	var s parser.Schema
+	typ, nullable := im.schemaType(n)
+	switch {
+	case get(n, "allOf") != nil:
+		s = im.allOf(n, hint)
+	case get(n, "oneOf") != nil:
+		s = im.union(n, get(n, "oneOf"), parser.TypeOneOf, hint)
+	case get(n, "anyOf") != nil:
+		s = im.union(n, get(n, "anyOf"), parser.TypeAnyOf, hint)
+	case typ == "array" || typ == "" && get(n, "items") != nil:
+		s = im.array(n, hint)
+	case typ == "object" || typ == "" && (get(n, "properties") != nil || get(n, "additionalProperties") != nil):
+		s = im.object(n, hint)
+	case typ == "string":
+		format := str(n, "format")
+		t, ok := stringFormats[format]
+		if !ok {
+			im.warn(n, "string format `"+format+"` is not supported")
+			t = parser.TypeString
+		}
+		s.Type = t
+	case typ == "integer":
+		s.Type = parser.TypeInt64
+		if str(n, "format") == "int32" {
+			s.Type = parser.TypeInt32
+		}
+	case typ == "number":
+		s.Type = parser.TypeDouble
+		if str(n, "format") == "float" {
+			s.Type = parser.TypeFloat
+		}
+	case typ == "boolean":
+		s.Type = parser.TypeBool
+	default:
+		s.Type = parser.TypeAny
+	}
+
+	s.Nullable = s.Nullable || nullable || str(n, "nullable") == "true"
+	s.Deprecated = str(n, "deprecated") == "true"
+	s.Description = strings.TrimSpace(str(n, "description"))
+	im.enum(n, &s)
+	im.constraints(n, &s)
+	if def := get(n, "default"); def != nil {
+		if def.Kind == yaml.ScalarNode && !s.IsArray && !s.IsMap && s.Type != parser.TypeObject {
+			s.Default = def.Value
+			if err := parser.CheckConstraints(s.Constraints, s.Type, s.IsArray, s.Default); err != nil {
+				im.warn(def, "default value is skipped: "+err.Error())
+				s.Default = ""
+			}
+		} else {
+			im.warn(def, "default value is supported for scalars only")
+		}
+	}
+	if ex := get(n, "example"); ex != nil {
+		s.Example = example(ex)
+	} else if ex := get(n, "examples"); ex != nil && ex.Kind == yaml.SequenceNode && len(ex.Content) != 0 {
+		s.Example = example(ex.Content[0])
+	}
+	if _, err := parser.ParseExample(s); err != nil {
+		im.warn(n, "incorrect example is skipped: "+err.Error())
+		s.Example = ""
+	}
+	return s
+}
+
+// schemaType returns type of the schema, type lists of OpenAPI 3.1 are supported with `null` only
+func (im *importer) schemaType(n *yaml.Node) (string, bool) {
+	t := get(n, "type")
+	if t == nil {
+		return "", false
+	}
+	if t.Kind == yaml.ScalarNode {
+		return t.Value, false
+	}
+	var res string
+	var nullable bool
+	for _, v := range seq(t) {
+		switch {
+		case v.Value == "null":
+			nullable = true
+		case res == "":
+			res = v.Value
+		default:
+			im.warn(t, "several types are not supported, use oneOf")
+			return "", nullable
+		}
+	}
+	return res, nullable
+}
+
+func (im *importer) array(n *yaml.Node, hint string) parser.Schema {
+	item := im.schema(get(n, "items"), hint+"Item")
+	if item.Nullable {
+		im.warn(n, "nullable array items are not supported")
+	}
+	if isInlineObject(item) || item.IsArray {
+		item = parser.Schema{Type: im.hoist(item, hint+"Item")}
+	}
+	return parser.Schema{
+		Type:          item.Type,
+		Format:        item.Format,
+		IsArray:       true,
+		IsMap:         item.IsMap,
+		Enum:          item.Enum,
+		Constraints:   item.Constraints,
+		Variants:      item.Variants,
+		Discriminator: item.Discriminator,
+	}
+}
+
+func (im *importer) object(n *yaml.Node, hint string) parser.Schema {
+	props := pairs(get(n, "properties"))
+	if ap := get(n, "additionalProperties"); ap != nil && ap.Value != "false" {
+		if len(props) != 0 {
+			im.warn(ap, "additional properties of an object with properties are not supported")
+		} else {
+			value := parser.Schema{Type: parser.TypeAny}
+			if ap.Kind == yaml.MappingNode {
+				value = im.schema(ap, hint+"Value")
+			}
+			if value.Nullable {
+				im.warn(ap, "nullable map values are not supported")
+			}
+			if isInlineObject(value) || value.IsMap || value.IsArray {
+				value = parser.Schema{Type: im.hoist(value, hint+"Value")}
+			}
+			return parser.Schema{
+				Type:          value.Type,
+				Format:        value.Format,
+				IsMap:         true,
+				Enum:          value.Enum,
+				Variants:      value.Variants,
+				Discriminator: value.Discriminator,
+			}
+		}
+	}
+
+	required := map[string]struct{}{}
+	for _, r := range seq(get(n, "required")) {
+		required[r.Value] = struct{}{}
+	}
+	s := parser.Schema{Type: parser.TypeObject}
+	for _, p := range props {
+		if p.Left.Value == parser.LongFormType {
+			im.warn(p.Left, "field `"+parser.LongFormType+"` is skipped, it is the key of the long form declaration")
+			continue
+		}
+		_, req := required[p.Left.Value]
+		s.Fields = append(s.Fields, im.field(p.Left.Value, p.Right, req, hint+identifier(p.Left.Value)))
+	}
+	return s
+}
+
+// allOf converts composition to the object with embeds, single reference is kept as is
+func (im *importer) allOf(n *yaml.Node, hint string) parser.Schema {
+	parts := seq(get(n, "allOf"))
+	if len(parts) == 1 && str(parts[0], "$ref") != "" {
+		return im.schema(parts[0], hint)
+	}
+
+	s := parser.Schema{Type: parser.TypeObject}
+	for _, part := range parts {
+		p := im.schema(part, hint)
+		switch {
+		case p.Type.IsRef() && !p.IsArray && !p.IsMap:
+			s.Embeds = append(s.Embeds, p.Type)
+		case p.Type == parser.TypeObject && !p.IsArray && !p.IsMap:
+			s.Embeds = append(s.Embeds, p.Embeds...)
+			s.Fields = parser.MergeFields(p.Fields, s.Fields)
+		default:
+			im.warn(part, "only objects and references are supported in allOf")
+		}
+	}
+	if get(n, "properties") != nil {
+		p := im.object(n, hint)
+		s.Fields = parser.MergeFields(p.Fields, s.Fields)
+	}
+	return s
+}
+
+func (im *importer) union(n, variants *yaml.Node, typ parser.Type, hint string) parser.Schema {
+	s := parser.Schema{Type: typ}
+	values := map[string]string{}
+	if disc := get(n, "discriminator"); disc != nil {
+		s.Discriminator.Property = str(disc, "propertyName")
+		for _, p := range pairs(get(disc, "mapping")) {
+			values[p.Right.Value] = p.Left.Value
+		}
+	}
+
+	for i, v := range seq(variants) {
+		if str(v, "type") == "null" {
+			s.Nullable = true
+			continue
+		}
+		variant := im.schema(v, fmt.Sprintf("%sOption%d", hint, i+1))
+		if !variant.Type.IsRef() || variant.IsArray || variant.IsMap {
+			isScalar := !variant.IsArray && !variant.IsMap && variant.Type != parser.TypeObject && !variant.Type.IsUnion() &&
+				variant.Enum == nil && variant.Constraints.IsEmpty()
+			if !isScalar || s.Discriminator.Property != "" {
+				variant = parser.Schema{Type: im.hoist(variant, fmt.Sprintf("%sOption%d", hint, i+1))}
+			}
+		}
+		s.Variants = append(s.Variants, variant.Type)
+		if value, ok := values[str(v, "$ref")]; ok {
+			s.Discriminator.Mapping = append(s.Discriminator.Mapping, parser.DiscriminatorMapping{Value: value, Type: variant.Type})
+		}
+	}
+	if len(s.Variants) == 0 {
+		im.warn(variants, "union without variants is not supported")
+		return parser.Schema{Type: parser.TypeAny, Nullable: s.Nullable}
+	}
+	return s
+}
+
+var enumValueRe = regexp.MustCompile(`^[^,()\s]+$`)
+
+func (im *importer) enum(n *yaml.Node, s *parser.Schema) {
+	values := get(n, "enum")
+	if values == nil {
+		return
+	}
+	if s.Type != parser.TypeString {
+		im.warn(values, "enums are supported for strings only")
+		return
+	}
+	enum := []string{}
+	for _, v := range seq(values) {
+		if v.Tag == "!!null" {
+			s.Nullable = true
+			continue
+		}
+		if !enumValueRe.MatchString(v.Value) {
+			im.warn(v, "enum value `"+v.Value+"` is not supported")
+			return
+		}
+		enum = append(enum, v.Value)
+	}
+	s.Type = parser.TypeEnum
+	s.Enum = enum
+}
+
+func (im *importer) constraints(n *yaml.Node, s *parser.Schema) {
+	c := &s.Constraints
+	float := func(key string) *float64 {
+		if v := get(n, key); v != nil {
+			if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+				return &f
+			}
+		}
+		return nil
+	}
+	integer := func(key string) *int64 {
+		if v := get(n, key); v != nil {
+			if i, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				return &i
+			}
+		}
+		return nil
+	}
+
+	if v := float("minimum"); v != nil {
+		c.Min = v
+	}
+	if v := float("maximum"); v != nil {
+		c.Max = v
+	}
+	// Exclusive bounds are booleans in OpenAPI 3.0 and numbers in OpenAPI 3.1
+	if v := get(n, "exclusiveMinimum"); v != nil {
+		if v.Value == "true" {
+			c.ExclusiveMin = c.Min != nil
+		} else if f := float("exclusiveMinimum"); f != nil {
+			c.Min, c.ExclusiveMin = f, true
+		}
+	}
+	if v := get(n, "exclusiveMaximum"); v != nil {
+		if v.Value == "true" {
+			c.ExclusiveMax = c.Max != nil
+		} else if f := float("exclusiveMaximum"); f != nil {
+			c.Max, c.ExclusiveMax = f, true
+		}
+	}
+	if v := integer("minLength"); v != nil {
+		c.MinLength = v
+	}
+	if v := integer("maxLength"); v != nil {
+		c.MaxLength = v
+	}
+	if v := str(n, "pattern"); v != "" {
+		c.Pattern = v
+	}
+	if s.IsArray {
+		c.MinItems = integer("minItems")
+		c.MaxItems = integer("maxItems")
+		c.UniqueItems = str(n, "uniqueItems") == "true"
+	}
+
+	if err := parser.CheckConstraints(*c, s.Type, s.IsArray, ""); err != nil {
+		im.warn(n, "constraints are skipped: "+err.Error())
+		s.Constraints = parser.Constraints{}
+	}
+}
+
+// example returns example text, objects and arrays are converted to JSON
+func example(n *yaml.Node) string {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func (im *importer) paths() {
+	for _, p := range pairs(get(im.root, "paths")) {
+		item := im.resolve(p.Right)
+		for _, key := range []string{"servers", "$ref"} {
+			if n := get(item, key); n != nil && item == p.Right {
+				im.warn(n, "`"+key+"` of path is not supported")
+			}
+		}
+		for _, method := range httpMethods {
+			op := get(item, method)
+			if op == nil {
+				continue
+			}
+			if method == "trace" {
+				im.warn(op, "TRACE method is not supported")
+				continue
+			}
+			im.operation(p.Left.Value, strings.ToUpper(method), item, op)
+		}
+	}
+	im.flush()
+}
+
+func (im *importer) operation(path, method string, item, op *yaml.Node) {
+	m := parser.Method{
+		Method:     method,
+		Path:       path,
+		Name:       str(op, "operationId"),
+		Deprecated: str(op, "deprecated") == "true",
+	}
+	summary := strings.TrimSpace(str(op, "summary"))
+	m.Description = strings.TrimSpace(summary + "\n" + strings.TrimSpace(str(op, "description")))
+
+	tags := seq(get(op, "tags"))
+	m.Tag = "default"
+	if len(tags) != 0 {
+		m.Tag = tags[0].Value
+	}
+	if len(tags) > 1 {
+		im.warn(tags[1], "only the first tag of the method is imported")
+	}
+	im.addTag(m.Tag, "")
+
+	for _, key := range []string{"callbacks", "servers", "externalDocs"} {
+		if n := get(op, key); n != nil {
+			im.warn(n, "`"+key+"` of method is not supported")
+		}
+	}
+	if n := get(op, "security"); n != nil {
+		m.Security = im.security(n)
+	}
+
+	hint := m.Name
+	if hint == "" {
+		hint = method + " " + path
+	}
+	hint = identifier(hint)
+
+	im.parameters(&m, append(seq(get(item, "parameters")), seq(get(op, "parameters"))...), hint)
+	if body := get(op, "requestBody"); body != nil {
+		im.requestBody(&m, im.resolve(body), hint+"Request")
+	}
+	im.responses(&m, get(op, "responses"), hint+"Response")
+
+	im.doc.API.Methods = append(im.doc.API.Methods, m)
+}
+
+var forbiddenHeaders = map[string]struct{}{"Accept": {}, "Authorization": {}, "Content-Type": {}}
+
+// parameters converts parameters of the method, parameters of the operation override parameters of the path
+func (im *importer) parameters(m *parser.Method, params []*yaml.Node, hint string) {
+	for _, ref := range params {
+		p := im.resolve(ref)
+		name := str(p, "name")
+		in := str(p, "in")
+		for _, key := range []string{"style", "explode", "allowReserved", "allowEmptyValue", "content"} {
+			if n := get(p, key); n != nil {
+				im.warn(n, "`"+key+"` of parameter `"+name+"` is not supported")
+			}
+		}
+
+		s := im.field(name, get(p, "schema"), str(p, "required") == "true" || in == "path", hint+identifier(name))
+		if d := strings.TrimSpace(str(p, "description")); d != "" {
+			s.Description = d
+		}
+		if ex := get(p, "example"); ex != nil {
+			s.Example = example(ex)
+		}
+		s.Deprecated = s.Deprecated || str(p, "deprecated") == "true"
+
+		switch in {
+		case "path":
+			m.Request.Params = setParam(m.Request.Params, s)
+		case "query":
+			m.Request.Query = setParam(m.Request.Query, s)
+		case "header":
+			if _, ok := forbiddenHeaders[canonicalHeader(name)]; ok {
+				im.warn(p, "header `"+name+"` can't be declared as parameter")
+				continue
+			}
+			m.Request.Headers = setParam(m.Request.Headers, s)
+		case "cookie":
+			m.Request.Cookies = setParam(m.Request.Cookies, s)
+		default:
+			im.warn(p, "parameter `"+name+"` in `"+in+"` is not supported")
+		}
+	}
+}
+
+func setParam(params []parser.Schema, s parser.Schema) []parser.Schema {
+	for i := range params {
+		if params[i].Name == s.Name {
+			params[i] = s
+			return params
+		}
+	}
+	return append(params, s)
+}
+
+func canonicalHeader(name string) string {
+	parts := strings.Split(strings.ToLower(name), "-")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func (im *importer) requestBody(m *parser.Method, n *yaml.Node, hint string) {
+	body, ok := im.content(get(n, "content"), hint)
+	if !ok {
+		im.warn(n, "request body without content is not supported")
+		return
+	}
+	if str(n, "required") != "true" {
+		body.Optional = true
+		body = im.named(body, hint)
+	}
+	m.Request.Body = &body
+}
+
+func (im *importer) responses(m *parser.Method, n *yaml.Node, hint string) {
+	codes := pairs(n)
+	// Success response is 200 or the first 2xx response
+	for _, p := range codes {
+		if p.Left.Value == "200" {
+			m.Response.Code = "200"
+		}
+	}
+	for _, p := range codes {
+		if m.Response.Code == "" && len(p.Left.Value) == 3 && p.Left.Value[0] == '2' {
+			m.Response.Code = p.Left.Value
+		}
+	}
+
+	for _, p := range codes {
+		code := p.Left.Value
+		r := im.resolve(p.Right)
+		body, hasBody := im.content(get(r, "content"), hint+code)
+		var res *parser.Schema
+		if hasBody {
+			res = &body
+		}
+
+		im.responseKeys(r, code)
+
+		headers := pairs(get(r, "headers"))
+		if len(headers) != 0 && code != m.Response.Code {
+			im.warn(p.Left, "headers are supported for the success response only")
+		}
+
+		switch {
+		case code == "default":
+			if res == nil {
+				im.warn(p.Left, "default response without content is not supported")
+				continue
+			}
+			m.Response.Default = res
+		case code == m.Response.Code:
+			m.Response.Body = res
+			for _, h := range headers {
+				hn := im.resolve(h.Right)
+				s := im.field(h.Left.Value, get(hn, "schema"), str(hn, "required") == "true", hint+identifier(h.Left.Value))
+				if d := strings.TrimSpace(str(hn, "description")); d != "" {
+					s.Description = d
+				}
+				if canonicalHeader(s.Name) == "Content-Type" {
+					continue
+				}
+				m.Response.Headers = append(m.Response.Headers, s)
+			}
+		default:
+			if c, err := strconv.Atoi(code); err != nil || c < 100 || c > 599 {
+				im.warn(p.Left, "response code `"+code+"` is not supported")
+				continue
+			}
+			if m.Response.Codes == nil {
+				m.Response.Codes = map[string]*parser.Schema{}
+			}
+			m.Response.Codes[code] = res
+		}
+	}
+}
+
+// responseKeys warns about keys of the response which are not imported,
+// description is generated by agen, so only descriptions differing from the generated one are reported
+func (im *importer) responseKeys(n *yaml.Node, code string) {
+	genCode := code
+	if code == "default" {
+		genCode = ""
+	}
+	for _, p := range pairs(n) {
+		switch key := p.Left.Value; {
+		case key == "content" || key == "headers" || strings.HasPrefix(key, "x-"):
+		case key == "description":
+			if d := strings.TrimSpace(p.Right.Value); d != "" && d != gen.ResponseDescription(genCode) {
+				im.warn(p.Right, "description of response `"+code+"` is not imported")
+			}
+		default:
+			im.warn(p.Left, "`"+key+"` of response `"+code+"` is not supported")
+		}
+	}
+}
+
+// content converts media types of the body, media types with the same schema are joined,
+// false is returned for body without content
+func (im *importer) content(n *yaml.Node, hint string) (parser.Schema, bool) {
+	media := pairs(n)
+	if len(media) == 0 {
+		return parser.Schema{}, false
+	}
+
+	// Forms are declared with their own content type
+	for _, p := range media {
+		if p.Left.Value != parser.ContentTypeForm && p.Left.Value != parser.ContentTypeMultipart {
+			continue
+		}
+		if len(media) > 1 {
+			im.warn(n, "form is imported without other content types")
+		}
+		return im.form(p.Right, p.Left.Value, hint), true
+	}
+
+	// Binary bodies are files with content types in format
+	files := []string{}
+	for _, p := range media {
+		schema := get(p.Right, "schema")
+		if str(schema, "format") == "binary" || schema == nil && p.Left.Value != parser.ContentTypeJSON {
+			files = append(files, p.Left.Value)
+		}
+	}
+	if len(files) == len(media) {
+		s := parser.Schema{Type: parser.TypeFile}
+		if len(files) != 1 || files[0] != "application/octet-stream" {
+			s.Format = strings.Join(files, ",")
+		}
+		return s, true
+	}
+
+	// Media types are joined if their schemas are equal, JSON has priority
+	first := media[0]
+	for _, p := range media {
+		if p.Left.Value == parser.ContentTypeJSON {
+			first = p
+			break
+		}
+	}
+	key := nodeKey(get(first.Right, "schema"))
+	s := im.schema(get(first.Right, "schema"), hint)
+	if ex := get(first.Right, "example"); ex != nil {
+		s.Example = example(ex)
+	}
+	types := []string{}
+	for _, p := range media {
+		if nodeKey(get(p.Right, "schema")) != key {
+			im.warn(p.Left, "content type `"+p.Left.Value+"` with different schema is not supported")
+			continue
+		}
+		types = append(types, p.Left.Value)
+	}
+	if len(types) != 1 || types[0] != parser.ContentTypeJSON {
+		s.ContentTypes = types
+	}
+	return s, true
+}
+
+func (im *importer) form(n *yaml.Node, contentType, hint string) parser.Schema {
+	s := im.schema(get(n, "schema"), hint)
+	if !isInlineObject(s) && !s.Type.IsRef() || s.IsArray || s.IsMap {
+		im.warn(n, "form should be an object")
+		s = parser.Schema{Type: parser.TypeObject}
+	}
+	for _, p := range pairs(get(n, "encoding")) {
+		ct := str(p.Right, "contentType")
+		for i := range s.Fields {
+			if s.Fields[i].Name == p.Left.Value && s.Fields[i].Type == parser.TypeFile {
+				s.Fields[i].Format = ct
+			}
+		}
+	}
+	if contentType == parser.ContentTypeForm {
+		for i, f := range s.Fields {
+			if f.Type == parser.TypeFile {
+				im.warn(n, "file field `"+f.Name+"` is allowed in multipart form only")
+				s.Fields[i].Type = parser.TypeByte
+			}
+		}
+	}
+	s.ContentTypes = []string{contentType}
+	return s
+}
+
+// fillNames removes operation ids which are equal to generated names,
+// names are kept if removing changes generated names of other methods
+func (im *importer) fillNames() {
+	methods := im.doc.API.Methods
+	auto := make([]parser.Method, len(methods))
+	copy(auto, methods)
+	for i := range auto {
+		auto[i].Name = ""
+	}
+	if err := parser.FillMethodsNames(auto); err != nil {
+		return
+	}
+
+	names := make([]string, len(methods))
+	check := make([]parser.Method, len(methods))
+	copy(check, methods)
+	for i := range check {
+		if check[i].Name == auto[i].Name {
+			check[i].Name = ""
+		}
+		names[i] = check[i].Name
+	}
+	if err := parser.FillMethodsNames(check); err != nil {
+		return
+	}
+	for i := range check {
+		if methods[i].Name != "" && check[i].Name != methods[i].Name {
+			return
+		}
+	}
+	for i := range methods {
+		methods[i].Name = names[i]
+	}
+}
+
+// extractCommon moves responses and request headers shared by all methods to the common section
+func extractCommon(methods []parser.Method) *parser.Method {
+	if len(methods) < 2 {
+		return nil
+	}
+	common := parser.Method{}
+	first := methods[0]
+
+	same := func(get func(m *parser.Method) any) bool {
+		v := get(&methods[0])
+		for i := range methods[1:] {
+			if !reflect.DeepEqual(get(&methods[i+1]), v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if first.Response.Default != nil && same(func(m *parser.Method) any { return m.Response.Default }) {
+		common.Response.Default = first.Response.Default
+		for i := range methods {
+			methods[i].Response.Default = nil
+		}
+	}
+
+	codes := make([]string, 0, len(first.Response.Codes))
+	for code := range first.Response.Codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		shared := same(func(m *parser.Method) any {
+			s, ok := m.Response.Codes[code]
+			return struct {
+				S  *parser.Schema
+				Ok bool
+			}{s, ok}
+		})
+		if !shared {
+			continue
+		}
+		if common.Response.Codes == nil {
+			common.Response.Codes = map[string]*parser.Schema{}
+		}
+		common.Response.Codes[code] = first.Response.Codes[code]
+		for i := range methods {
+			delete(methods[i].Response.Codes, code)
+		}
+	}
+
+	for _, h := range first.Request.Headers {
+		shared := same(func(m *parser.Method) any {
+			for _, mh := range m.Request.Headers {
+				if mh.Name == h.Name {
+					return mh
+				}
+			}
+			return nil
+		})
+		if !shared {
+			continue
+		}
+		common.Request.Headers = append(common.Request.Headers, h)
+		for i := range methods {
+			headers := []parser.Schema{}
+			for _, mh := range methods[i].Request.Headers {
+				if mh.Name != h.Name {
+					headers = append(headers, mh)
+				}
+			}
+			methods[i].Request.Headers = headers
+		}
+	}
+
+	if common.Response.Default == nil && common.Response.Codes == nil && common.Request.Headers == nil {
+		return nil
+	}
+	return &common
+}
+
+// resolve follows local references (`#/components/...`), other nodes are returned as is
+func (im *importer) resolve(n *yaml.Node) *yaml.Node {
+	for depth := 0; depth < 32; depth++ {
+		ref := str(n, "$ref")
+		if !strings.HasPrefix(ref, "#/") {
+			return n
+		}
+		target := im.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			target = get(target, unescape(part))
+		}
+		if target == nil {
+			return n
+		}
+		n = target
+	}
+	return n
+}
+
+func unescape(part string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+}
+
+func get(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func str(n *yaml.Node, key string) string {
+	v := get(n, key)
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return v.Value
+}
+
+func pairs(n *yaml.Node) []parser.NodePair {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	res, err := parser.PairNodes(n)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+func seq(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// nodeKey returns text of the node to compare schemas
+func nodeKey(n *yaml.Node) string {
+	if n == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(n)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
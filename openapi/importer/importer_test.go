@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Kegian/agen/openapi/parser"
+)
+
+const spec = `openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+servers:
+  - url: /api/v1
+  - url: /api/v2
+paths:
+  /users/{id}:
+    get:
+      tags: [users]
+      operationId: getUser
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            format: int64
+        - name: fields
+          in: query
+          schema:
+            type: string
+            minLength: 1
+      responses:
+        '200':
+          description: Successful operation
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        '404':
+          description: Not found
+          links:
+            self:
+              operationId: getUser
+          x-internal: true
+    trace:
+      responses:
+        '200':
+          description: OK
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, status]
+      properties:
+        id:
+          type: integer
+          format: int64
+          description: ID
+          example: 1
+        status:
+          type: string
+          enum: [active, blocked]
+        age:
+          type: integer
+          format: int32
+          minimum: 18
+          default: 10
+        _type:
+          type: string
+`
+
+func TestImport(t *testing.T) {
+	res, diags, err := Import([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"6: only the first server is imported",
+		"60: default value is skipped: incorrect default value: should be greater than or equal to 18",
+		"61: field `_type` is skipped, it is the key of the long form declaration",
+		"33: description of response `404` is not imported",
+		"34: `links` of response `404` is not supported",
+		"39: TRACE method is not supported",
+	}
+	got := []string{}
+	for _, d := range diags {
+		if d.Severity != parser.SeverityWarning || d.Code != CodeUnsupported {
+			t.Errorf("got diagnostic %+v, want unsupported warning", d)
+		}
+		got = append(got, strconv.Itoa(d.Line)+": "+d.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	dsl, err := GenDSL(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDSL := `settings:
+  title: Users
+  version: 1.0.0
+  url: /api/v1
+  security: none
+
+api:
+  users:
+    'GET /users/{id}':
+      name: getUser
+      request:
+        params:
+          id: int64
+        query:
+          fields: string{min_length=1}?
+      response:
+        body: $User
+        404: empty
+
+schemas:
+  User:
+    id: int64 # ID (1)
+    status: enum(active,blocked)
+    age: int32{min=18}?
+`
+	if dsl != wantDSL {
+		t.Errorf("got:\n%s\nwant:\n%s", dsl, wantDSL)
+	}
+
+	// Generated file is valid
+	if _, err := parser.ParseDocument([]byte(dsl)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	for data, want := range map[string]string{
+		"openapi: [":               "did not find expected node content",
+		"- 1":                      "should be OpenAPI document",
+		"swagger: '2.0'\ninfo: {}": "only OpenAPI 3 documents are supported",
+	} {
+		if _, _, err := Import([]byte(data)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", data, err, want)
+		}
+	}
+}
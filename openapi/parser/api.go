@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"net/textproto"
 	"regexp"
@@ -11,25 +12,46 @@ import (
 )
 
 func ParseAPI(n *yaml.Node) (API, error) {
+	api, common, err := parseAPI(n)
+	if err != nil {
+		return API{}, err
+	}
+
+	if err := ApplyCommon(api.Methods, common); err != nil {
+		return API{}, err
+	}
+
+	if err := FillMethodsNames(api.Methods); err != nil {
+		return API{}, err
+	}
+
+	return api, nil
+}
+
+// parseAPI parses tags and methods, API level common section is returned separately
+func parseAPI(n *yaml.Node) (API, *Method, error) {
 	tags := []Tag{}
 	methods := []Method{}
 	var common *Method
 
 	pairs, err := PairNodes(n)
 	if err != nil {
-		return API{}, err
+		return API{}, nil, err
 	}
+	// Tags are parsed independently to report all errors
+	var errs Diagnostics
 	for _, p := range pairs {
 		name := p.Left.Value
 		switch name {
 		case "_common":
 			c, err := ParseCommon(p.Right)
 			if err != nil {
-				return API{}, err
+				errs.add(err)
+				continue
 			}
 			common = &c
 		default:
-			comment := ParseComment(p.Left.LineComment)
+			comment := ParseNodeComment(p.Left)
 
 			tag := Tag{
 				Name:        p.Left.Value,
@@ -39,27 +61,43 @@ func ParseAPI(n *yaml.Node) (API, error) {
 
 			m, err := ParseMethods(p.Right, tag.Name)
 			if err != nil {
-				return API{}, err
+				errs.add(err)
 			}
 			methods = append(methods, m...)
 		}
 	}
 
-	if common != nil {
-		for i := 0; i < len(methods); i++ {
-			methods[i].Request = MergeRequest(methods[i].Request, common.Request)
-			methods[i].Response = MergeResponse(methods[i].Response, common.Response)
-		}
-	}
-
-	if err := FillMethodsNames(methods); err != nil {
-		return API{}, err
-	}
-
 	return API{
 		Tags:    tags,
 		Methods: methods,
-	}, nil
+	}, common, errs.Err()
+}
+
+// ApplyCommon merges common section into the methods, method fields have priority
+func ApplyCommon(methods []Method, common *Method) error {
+	if common == nil {
+		return nil
+	}
+	for i := 0; i < len(methods); i++ {
+		req, err := MergeRequest(methods[i].Request, common.Request)
+		if err != nil {
+			return atPos(Err(nil, fmt.Sprintf("%s (method `%s %s`)", err.Error(), methods[i].Method, methods[i].Path)), methods[i].Pos)
+		}
+		methods[i].Security = MergeSecurity(methods[i].Security, common.Security)
+		methods[i].Request = req
+		methods[i].Response = MergeResponse(methods[i].Response, common.Response)
+	}
+	return nil
+}
+
+var httpMethods = map[string]struct{}{
+	"GET":     {},
+	"POST":    {},
+	"PUT":     {},
+	"PATCH":   {},
+	"DELETE":  {},
+	"HEAD":    {},
+	"OPTIONS": {},
 }
 
 func ParseMethods(n *yaml.Node, tag string) ([]Method, error) {
@@ -70,11 +108,14 @@ func ParseMethods(n *yaml.Node, tag string) ([]Method, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Methods are parsed independently to report all errors, common section is applied only without errors
+	var errs Diagnostics
 	for _, p := range pairs {
 		if p.Left.Value == "_common" {
 			c, err := ParseCommon(p.Right)
 			if err != nil {
-				return nil, err
+				errs.add(err)
+				continue
 			}
 			common = &c
 			continue
@@ -82,31 +123,42 @@ func ParseMethods(n *yaml.Node, tag string) ([]Method, error) {
 
 		m := strings.Split(p.Left.Value, " ")
 		if len(m) != 2 {
-			return nil, Err(p.Left, "incorrect method format")
+			errs.add(Err(p.Left, "incorrect method format"))
+			continue
 		}
-		if m[0] != "POST" && m[0] != "GET" {
-			// TODO: support all methods
-			return nil, Err(p.Left, "incorrect method type (POST/GET only)")
+		if _, ok := httpMethods[m[0]]; !ok {
+			errs.add(Err(p.Left, "incorrect method type (GET/POST/PUT/PATCH/DELETE/HEAD/OPTIONS only)"))
+			continue
 		}
 
-		comment := ParseComment(p.Left.LineComment)
+		comment := ParseNodeComment(p.Left)
 
 		method, err := ParseMethod(m[1], p.Right)
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			continue
 		}
 		method.Method = m[0]
-		method.Description = comment.Description
+		method.Pos = Pos{Line: p.Left.Line, Column: p.Left.Column}
+		if method.Description == "" {
+			method.Description = comment.Description
+		}
 		method.Tag = tag
 
+		if method.Method == "HEAD" && method.Response.Body != nil {
+			errs.add(Err(p.Left, "HEAD method can't have response body"))
+			continue
+		}
+
 		methods = append(methods, method)
 	}
 
-	if common != nil {
-		for i := 0; i < len(methods); i++ {
-			methods[i].Request = MergeRequest(methods[i].Request, common.Request)
-			methods[i].Response = MergeResponse(methods[i].Response, common.Response)
-		}
+	if errs.HasErrors() {
+		return methods, errs.Err()
+	}
+
+	if err := ApplyCommon(methods, common); err != nil {
+		return nil, err
 	}
 
 	return methods, nil
@@ -120,6 +172,11 @@ func ParseCommon(n *yaml.Node) (Method, error) {
 	}
 	for _, p := range pairs {
 		switch p.Left.Value {
+		case "security":
+			method.Security, err = ParseSecurity(p.Right)
+			if err != nil {
+				return Method{}, err
+			}
 		case "request":
 			method.Request, err = ParseRequest(p.Right)
 			if err != nil {
@@ -140,14 +197,31 @@ func ParseCommon(n *yaml.Node) (Method, error) {
 
 func ParseMethod(path string, n *yaml.Node) (Method, error) {
 	method := Method{}
-	pairs, err := PairNodes(n)
-	if err != nil {
-		return Method{}, err
+	var pairs []NodePair
+	var err error
+	// Methods without request and response (e.g. `DELETE /users/{id}:`) are allowed
+	if !isNull(n) {
+		pairs, err = PairNodes(n)
+		if err != nil {
+			return Method{}, err
+		}
 	}
 	for _, p := range pairs {
 		switch p.Left.Value {
 		case "name":
 			method.Name = strings.TrimSpace(p.Right.Value)
+		case "description":
+			method.Description = strings.TrimSpace(p.Right.Value)
+		case "deprecated":
+			method.Deprecated, err = strconv.ParseBool(strings.TrimSpace(p.Right.Value))
+			if err != nil {
+				return Method{}, Err(p.Right, "deprecated should be bool")
+			}
+		case "security":
+			method.Security, err = ParseSecurity(p.Right)
+			if err != nil {
+				return Method{}, err
+			}
 		case "request":
 			method.Request, err = ParseRequest(p.Right)
 			if err != nil {
@@ -177,7 +251,14 @@ func ParseRequest(n *yaml.Node) (Request, error) {
 		return Request{}, err
 	}
 	for _, p := range pairs {
-		switch p.Left.Value {
+		key, contentTypes, err := ParseContentTypes(p.Left)
+		if err != nil {
+			return Request{}, err
+		}
+		if len(contentTypes) != 0 && key != "body" {
+			return Request{}, Err(p.Left, "content types are allowed for body only")
+		}
+		switch key {
 		case "params":
 			req.Params, err = ParseParams(&p)
 			if err != nil {
@@ -193,10 +274,23 @@ func ParseRequest(n *yaml.Node) (Request, error) {
 			if err != nil {
 				return Request{}, err
 			}
-		case "form":
-			return Request{}, Err(p.Left, "unimplemented")
-		case "body":
-			req.Body, err = ParseBody(&p)
+		case "cookies":
+			req.Cookies, err = ParseCookies(&p)
+			if err != nil {
+				return Request{}, err
+			}
+		case "body", "form", "multipart":
+			if req.Body != nil {
+				return Request{}, Err(p.Left, "only one of body/form/multipart is allowed")
+			}
+			switch key {
+			case "form":
+				req.Body, err = ParseForm(&p, ContentTypeForm)
+			case "multipart":
+				req.Body, err = ParseForm(&p, ContentTypeMultipart)
+			default:
+				req.Body, err = ParseBody(&p, contentTypes)
+			}
 			if err != nil {
 				return Request{}, err
 			}
@@ -211,7 +305,9 @@ func ParsePathParams(path string) (string, []Schema) {
 	re := regexp.MustCompile(`\{(\w+)(\:[\$\w]+){0,1}\}`)
 	newPath := re.ReplaceAllString(path, "{$1}")
 
-	params := map[string]Schema{}
+	// Params are kept in order of the path, repeated param is declared by its last occurrence
+	res := []Schema{}
+	idx := map[string]int{}
 	groups := re.FindAllSubmatch([]byte(path), -1)
 	for _, g := range groups {
 		pName := string(g[1])
@@ -219,16 +315,17 @@ func ParsePathParams(path string) (string, []Schema) {
 		if string(g[2]) != "" {
 			pType = Type(string(g[2])[1:])
 		}
-		params[pName] = Schema{
+		param := Schema{
 			Name:     pName,
 			Type:     pType,
 			Optional: false,
 		}
-	}
-
-	res := []Schema{}
-	for _, s := range params {
-		res = append(res, s)
+		if i, ok := idx[pName]; ok {
+			res[i] = param
+			continue
+		}
+		idx[pName] = len(res)
+		res = append(res, param)
 	}
 
 	return newPath, res
@@ -256,9 +353,30 @@ func ParseQuery(p *NodePair) ([]Schema, error) {
 	return query.Fields, nil
 }
 
+func ParseCookies(p *NodePair) ([]Schema, error) {
+	cookies, err := ParseSchema(p)
+	if err != nil {
+		return nil, err
+	}
+	if cookies.Type != TypeObject {
+		return nil, Err(p.Right, "incorrect cookies format")
+	}
+	return cookies.Fields, nil
+}
+
 var forbiddenHeaders = map[string]struct{}{"Accept": {}, "Authorization": {}, "Content-Type": {}}
 
+var forbiddenResponseHeaders = map[string]struct{}{"Content-Type": {}}
+
 func ParseHeaders(p *NodePair) ([]Schema, error) {
+	return parseHeaders(p, forbiddenHeaders)
+}
+
+func ParseResponseHeaders(p *NodePair) ([]Schema, error) {
+	return parseHeaders(p, forbiddenResponseHeaders)
+}
+
+func parseHeaders(p *NodePair, forbidden map[string]struct{}) ([]Schema, error) {
 	headers, err := ParseSchema(p)
 	if err != nil {
 		return nil, err
@@ -270,28 +388,121 @@ func ParseHeaders(p *NodePair) ([]Schema, error) {
 		headers.Fields[i].Name = textproto.CanonicalMIMEHeaderKey(f.Name)
 	}
 	for _, f := range headers.Fields {
-		if _, ok := forbiddenHeaders[f.Name]; ok {
+		if _, ok := forbidden[f.Name]; ok {
 			return nil, Err(p.Right, "forbidden header "+f.Name)
 		}
 	}
 	return headers.Fields, nil
 }
 
-func ParseBody(p *NodePair) (*Schema, error) {
+func ParseBody(p *NodePair, contentTypes []string) (*Schema, error) {
 	body, err := ParseSchema(p)
 	if err != nil {
 		return nil, err
 	}
+	body.ContentTypes = contentTypes
 	return &body, nil
 }
 
-func MergeRequest(prior, minor Request) Request {
+var contentTypeAliases = map[string]string{
+	"json":   ContentTypeJSON,
+	"text":   ContentTypeText,
+	"csv":    ContentTypeCSV,
+	"xml":    ContentTypeXML,
+	"ndjson": ContentTypeNDJSON,
+	"sse":    ContentTypeEventStream,
+}
+
+// ParseContentTypes splits body key to its name and content types, e.g. `body(json,csv)`,
+// content types are set by aliases (json/text/csv/xml/ndjson/sse) or by full media types
+func ParseContentTypes(n *yaml.Node) (string, []string, error) {
+	key := strings.TrimSpace(n.Value)
+	lp := strings.Index(key, "(")
+	if lp < 0 || !strings.HasSuffix(key, ")") {
+		return key, nil, nil
+	}
+
+	types := []string{}
+	unique := map[string]struct{}{}
+	for _, t := range strings.Split(key[lp+1:len(key)-1], ",") {
+		t = strings.TrimSpace(t)
+		if alias, ok := contentTypeAliases[t]; ok {
+			t = alias
+		}
+		if !strings.Contains(t, "/") {
+			return "", nil, Err(n, "incorrect content type `"+t+"`")
+		}
+		if _, ok := unique[t]; ok {
+			return "", nil, Err(n, "duplicate content type `"+t+"`")
+		}
+		unique[t] = struct{}{}
+		types = append(types, t)
+	}
+
+	return strings.TrimSpace(key[:lp]), types, nil
+}
+
+// ParseForm parses urlencoded or multipart form body, files are allowed in multipart form only
+func ParseForm(p *NodePair, contentType string) (*Schema, error) {
+	form, err := ParseSchema(p)
+	if err != nil {
+		return nil, err
+	}
+	if form.Type != TypeObject && !form.Type.IsRef() || form.IsArray {
+		return nil, Err(p.Right, "incorrect form format")
+	}
+	if contentType == ContentTypeForm {
+		for _, f := range form.Fields {
+			if f.Type == TypeFile {
+				return nil, Err(p.Right, "file field `"+f.Name+"` is allowed in multipart form only")
+			}
+		}
+	}
+	form.ContentTypes = []string{contentType}
+	return &form, nil
+}
+
+func MergeRequest(prior, minor Request) (Request, error) {
 	var res Request
 	res.Params = MergeParams(prior.Params, minor.Params)
 	res.Query = MergeParams(prior.Query, minor.Query)
 	res.Headers = MergeParams(prior.Headers, minor.Headers)
-	res.Body = prior.Body // TODO: merge bodies?
-	return res
+	res.Cookies = MergeParams(prior.Cookies, minor.Cookies)
+
+	body, err := MergeBody(prior.Body, minor.Body)
+	if err != nil {
+		return Request{}, err
+	}
+	res.Body = body
+
+	return res, nil
+}
+
+// MergeBody adds fields of the common body to the method body with the same precedence as MergeParams,
+// common custom types are embedded, common body is not applied to methods without body.
+// Method body of a custom type keeps the reference, common fields are added to it (`allOf` in the spec)
+func MergeBody(prior, minor *Schema) (*Schema, error) {
+	if prior == nil || minor == nil {
+		return prior, nil
+	}
+	if !isMergeableBody(prior) || !isMergeableBody(minor) {
+		return nil, errors.New("body can't be merged with common body, both should be objects or custom types")
+	}
+
+	res := *prior
+	res.Fields = MergeParams(prior.Fields, minor.Fields)
+	res.Embeds = append(append([]Type{}, prior.Embeds...), minor.Embeds...)
+	if minor.Type.IsRef() {
+		res.Embeds = append(res.Embeds, minor.Type)
+	}
+	return &res, nil
+}
+
+func isMergeableBody(s *Schema) bool {
+	if s.IsArray || s.IsMap || s.Nullable {
+		return false
+	}
+	return s.Type == TypeObject || s.Type.IsRef()
 }
 
 func MergeParams(prior, minor []Schema) []Schema {
@@ -320,42 +531,94 @@ func MergeParams(prior, minor []Schema) []Schema {
 	return res
 }
 
+// ParseResponse parses response section, `body` is a shorthand for the 200 response.
+// The first declared 2xx code becomes the success response, other codes are stored by the status code,
+// headers are sent with the success response
 func ParseResponse(n *yaml.Node) (Response, error) {
 	res := Response{
-		Errors: map[string]*Schema{},
+		Codes: map[string]*Schema{},
 	}
 	pairs, err := PairNodes(n)
 	if err != nil {
 		return Response{}, err
 	}
 	for _, p := range pairs {
-		switch p.Left.Value {
+		key, contentTypes, err := ParseContentTypes(p.Left)
+		if err != nil {
+			return Response{}, err
+		}
+		switch key {
 		case "body":
-			res.Body, err = ParseBody(&p)
+			if res.Code != "" {
+				return Response{}, Err(p.Left, "success response is already declared with code "+res.Code)
+			}
+			res.Code = "200"
+			res.Body, err = ParseResponseBody(&p, contentTypes)
+			if err != nil {
+				return Response{}, err
+			}
+		case "headers":
+			if len(contentTypes) != 0 {
+				return Response{}, Err(p.Left, "content types are allowed for bodies only")
+			}
+			res.Headers, err = ParseResponseHeaders(&p)
 			if err != nil {
 				return Response{}, err
 			}
 		case "default":
-			res.Default, err = ParseBody(&p)
+			res.Default, err = ParseBody(&p, contentTypes)
 			if err != nil {
 				return Response{}, err
 			}
 		default:
-			_, err := strconv.Atoi(p.Left.Value)
-			if err != nil {
-				return Response{}, Err(p.Left, "unknown field of a request")
+			code, err := strconv.Atoi(key)
+			if err != nil || code < 100 || code > 599 {
+				return Response{}, Err(p.Left, "unknown field of a response")
+			}
+			if _, ok := res.Codes[key]; ok || key == res.Code {
+				return Response{}, Err(p.Left, "duplicate response code")
 			}
-			res.Errors[p.Left.Value], err = ParseBody(&p)
+			body, err := ParseResponseBody(&p, contentTypes)
 			if err != nil {
 				return Response{}, err
 			}
+			if body != nil && (code == 204 || code == 205 || code == 304) {
+				return Response{}, Err(p.Right, "response with code "+key+" can't have body")
+			}
+			if res.Code == "" && code >= 200 && code < 300 {
+				res.Code = key
+				res.Body = body
+				continue
+			}
+			res.Codes[key] = body
 		}
 	}
 	return res, nil
 }
 
+// ParseResponseBody parses response body, `empty` value means response without body
+func ParseResponseBody(p *NodePair, contentTypes []string) (*Schema, error) {
+	if p.Right.Kind == yaml.ScalarNode && strings.TrimSpace(p.Right.Value) == "empty" {
+		if len(contentTypes) != 0 {
+			return nil, Err(p.Left, "empty response can't have content types")
+		}
+		return nil, nil
+	}
+	return ParseBody(p, contentTypes)
+}
+
+// MergeSecurity returns prior requirements if they are set (including empty ones)
+func MergeSecurity(prior, minor []SecurityRequirement) []SecurityRequirement {
+	if prior != nil {
+		return prior
+	}
+	return minor
+}
+
 func MergeResponse(prior, minor Response) Response {
 	var res Response
+	res.Code = prior.Code
+	res.Headers = MergeParams(prior.Headers, minor.Headers)
 	res.Body = prior.Body
 
 	res.Default = minor.Default
@@ -363,12 +626,14 @@ func MergeResponse(prior, minor Response) Response {
 		res.Default = prior.Default
 	}
 
-	res.Errors = map[string]*Schema{}
-	for k, v := range minor.Errors {
-		res.Errors[k] = v
+	res.Codes = map[string]*Schema{}
+	for k, v := range minor.Codes {
+		if k != res.Code {
+			res.Codes[k] = v
+		}
 	}
-	for k, v := range prior.Errors {
-		res.Errors[k] = v
+	for k, v := range prior.Codes {
+		res.Codes[k] = v
 	}
 
 	return res
@@ -388,48 +653,76 @@ func FillMethodsNames(methods []Method) error {
 		if m.Name != "" {
 			count[m.Name]++
 			if path, ok := unique[m.Name]; ok {
-				return Err(
+				return atPos(ErrCode(
 					nil,
+					CodeDuplicate,
 					fmt.Sprintf(
 						"Duplicate method name (%s) `%s` and `%s %s`",
 						m.Name, path, m.Method, m.Path,
 					),
-				)
+				), m.Pos)
 			}
 			unique[m.Name] = m.Method + " " + m.Path
 		}
 	}
 
-	paramsRe := regexp.MustCompile(`\{(\w+)(\:[\$\w]+){0,1}\}`)
-	underRe := regexp.MustCompile(`_+`)
 	for _, m := range nonames {
-		name := paramsRe.ReplaceAllString(m.Path, "_")
-		name = strings.ReplaceAll(name, "/", "_")
-		name = underRe.ReplaceAllString(name, "_")
-		name = strings.Trim(name, "_")
-		name = SnakeToUpper(name)
-
-		m.Name = name
-		count[name]++
+		m.Name = methodPathName(m.Path, false)
+		count[m.Name]++
 	}
 
+	// Methods with the same name are distinguished by the method type, e.g. `GetUsers` and `PostUsers`,
+	// and if it is not enough by path params too, e.g. `DeleteUsers` and `DeleteUsersUserID`
+	prefixed := make([]*Method, 0, len(nonames))
+	prefixedCount := map[string]int{}
 	for _, m := range nonames {
 		if count[m.Name] <= 1 {
 			continue
 		}
-		name := SnakeToUpper(m.Method) + m.Name
-		if path, ok := unique[name]; ok {
+		m.Name = SnakeToUpper(m.Method) + methodPathName(m.Path, false)
+		prefixedCount[m.Name]++
+		prefixed = append(prefixed, m)
+	}
+	for _, m := range prefixed {
+		if prefixedCount[m.Name] > 1 {
+			m.Name = SnakeToUpper(m.Method) + methodPathName(m.Path, true)
+		}
+	}
+
+	for _, m := range prefixed {
+		if path, ok := unique[m.Name]; ok {
 			return Err(
 				nil,
 				fmt.Sprintf(
 					"Duplicate method name (%s) `%s` and `%s %s`",
-					name, path, m.Method, m.Path,
+					m.Name, path, m.Method, m.Path,
 				),
 			)
 		}
-		m.Name = name
-		unique[name] = m.Method + " " + m.Path
+		unique[m.Name] = m.Method + " " + m.Path
 	}
 
 	return nil
 }
+
+var (
+	pathParamsRe = regexp.MustCompile(`\{(\w+)(\:[\$\w]+){0,1}\}`)
+	underRe      = regexp.MustCompile(`_+`)
+	idSuffixRe   = regexp.MustCompile(`([a-z0-9])Id($|[A-Z])`)
+)
+
+func methodPathName(path string, withParams bool) string {
+	repl := "_"
+	if withParams {
+		repl = "_${1}_"
+	}
+	name := pathParamsRe.ReplaceAllString(path, repl)
+	name = strings.ReplaceAll(name, "/", "_")
+	name = underRe.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	name = SnakeToUpper(name)
+	if withParams {
+		name = idSuffixRe.ReplaceAllString(name, "${1}ID${2}")
+	}
+	return name
+}
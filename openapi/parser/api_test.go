@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFillMethodsNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []Method
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "unique path",
+			methods: []Method{{Method: "GET", Path: "/users/{user_id}"}},
+			want:    []string{"Users"},
+		},
+		{
+			name: "same path",
+			methods: []Method{
+				{Method: "GET", Path: "/users"},
+				{Method: "POST", Path: "/users"},
+			},
+			want: []string{"GetUsers", "PostUsers"},
+		},
+		{
+			name: "same path without params",
+			methods: []Method{
+				{Method: "GET", Path: "/users"},
+				{Method: "POST", Path: "/users/{id}"},
+			},
+			want: []string{"GetUsers", "PostUsers"},
+		},
+		{
+			name: "path params",
+			methods: []Method{
+				{Method: "GET", Path: "/users"},
+				{Method: "GET", Path: "/users/{user_id}"},
+				{Method: "DELETE", Path: "/users"},
+				{Method: "DELETE", Path: "/users/{user_id}"},
+				{Method: "PUT", Path: "/users/{user_id}"},
+			},
+			want: []string{"GetUsers", "GetUsersUserID", "DeleteUsers", "DeleteUsersUserID", "PutUsers"},
+		},
+		{
+			name: "explicit names",
+			methods: []Method{
+				{Method: "GET", Path: "/users", Name: "ListUsers"},
+				{Method: "GET", Path: "/users/{id}"},
+			},
+			want: []string{"ListUsers", "Users"},
+		},
+		{
+			name: "duplicate explicit names",
+			methods: []Method{
+				{Method: "GET", Path: "/users", Name: "Users"},
+				{Method: "GET", Path: "/accounts", Name: "Users"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FillMethodsNames(tt.methods)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, m := range tt.methods {
+				if m.Name != tt.want[i] {
+					t.Errorf("%s %s: got name %s, want %s", m.Method, m.Path, m.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	doc := mustParse(t, `
+api:
+  users:
+    'PUT /users/{user_id}':
+      request:
+        body: $User
+    'DELETE /users/{user_id}':
+    'HEAD /users':
+schemas:
+  User:
+    name: string
+`)
+	want := []string{"PUT", "DELETE", "HEAD"}
+	if len(doc.API.Methods) != len(want) {
+		t.Fatalf("got %d methods, want %d", len(doc.API.Methods), len(want))
+	}
+	for i, m := range doc.API.Methods {
+		if m.Method != want[i] {
+			t.Errorf("got method %s, want %s", m.Method, want[i])
+		}
+	}
+
+	for name, data := range map[string]string{
+		"unknown method":     "api:\n  users:\n    'TRACE /users':\n",
+		"incorrect format":   "api:\n  users:\n    'GET':\n",
+		"HEAD with response": "api:\n  users:\n    'HEAD /users':\n      response:\n        body: string\n",
+	} {
+		if _, err := ParseDocument([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func mustParse(t *testing.T, data string) Document {
+	t.Helper()
+	doc, err := ParseDocument([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMergeBody(t *testing.T) {
+	doc := mustParse(t, `
+api:
+  _common:
+    request:
+      body:
+        client_info: string
+        name: int64
+  users:
+    'POST /users':
+      request:
+        body: $CreateUser
+    'POST /tags':
+      request:
+        body:
+          name: string
+    'GET /users':
+schemas:
+  CreateUser:
+    name: string
+`)
+	ref := doc.API.Methods[0].Request.Body
+	if ref.Type != "$CreateUser" {
+		t.Errorf("reference body: got type %s, want $CreateUser", ref.Type)
+	}
+	// Fields of the referenced schema take precedence over common fields
+	if got := fieldNames(ref.Fields); strings.Join(got, ",") != "client_info" {
+		t.Errorf("reference body: got added fields %v, want [client_info]", got)
+	}
+
+	inline := doc.API.Methods[1].Request.Body
+	if got := fieldNames(inline.Fields); strings.Join(got, ",") != "client_info,name" || inline.Fields[1].Type != TypeString {
+		t.Errorf("inline body: got fields %v", got)
+	}
+
+	if doc.API.Methods[2].Request.Body != nil {
+		t.Error("common body shouldn't be applied to methods without body")
+	}
+
+	_, err := ParseDocument([]byte(`
+api:
+  _common:
+    request:
+      body:
+        client_info: string
+  users:
+    'POST /users':
+      request:
+        body: string[]
+`))
+	if err == nil || !strings.Contains(err.Error(), "can't be merged with common body") {
+		t.Errorf("got error %v, want merge error", err)
+	}
+}
@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Constraints struct {
+	Min          *float64
+	Max          *float64
+	ExclusiveMin bool
+	ExclusiveMax bool
+	MinLength    *int64
+	MaxLength    *int64
+	Pattern      string
+	MinItems     *int64
+	MaxItems     *int64
+	UniqueItems  bool
+}
+
+func (c *Constraints) IsEmpty() bool {
+	return !c.HasValue() && !c.HasItems()
+}
+
+// HasValue reports if there are constraints applied to a value itself
+func (c *Constraints) HasValue() bool {
+	return c.Min != nil || c.Max != nil || c.MinLength != nil || c.MaxLength != nil || c.Pattern != ""
+}
+
+// HasItems reports if there are constraints applied to an array
+func (c *Constraints) HasItems() bool {
+	return c.MinItems != nil || c.MaxItems != nil || c.UniqueItems
+}
+
+// ParseConstraints parses constraints block content,
+// e.g. `min=1,max=100` or `min_length=3,pattern=^[a-z]+$`
+func ParseConstraints(val string) (Constraints, error) {
+	res := Constraints{}
+	for _, item := range splitList(val) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		value = unquote(strings.TrimSpace(value))
+		if !hasValue && key != "unique_items" {
+			return Constraints{}, errors.New("constraint `" + key + "` should have a value")
+		}
+
+		var err error
+		switch key {
+		case "min":
+			res.Min, err = parseFloat(key, value)
+		case "max":
+			res.Max, err = parseFloat(key, value)
+		case "exclusive_min":
+			res.Min, err = parseFloat(key, value)
+			res.ExclusiveMin = true
+		case "exclusive_max":
+			res.Max, err = parseFloat(key, value)
+			res.ExclusiveMax = true
+		case "min_length":
+			res.MinLength, err = parseInt(key, value)
+		case "max_length":
+			res.MaxLength, err = parseInt(key, value)
+		case "pattern":
+			if _, err := regexp.Compile(value); err != nil {
+				return Constraints{}, errors.New("incorrect pattern: " + err.Error())
+			}
+			res.Pattern = value
+		case "min_items":
+			res.MinItems, err = parseInt(key, value)
+		case "max_items":
+			res.MaxItems, err = parseInt(key, value)
+		case "unique_items":
+			res.UniqueItems = true
+			if hasValue {
+				res.UniqueItems, err = strconv.ParseBool(value)
+				if err != nil {
+					err = errors.New("constraint `" + key + "` should be a boolean")
+				}
+			}
+		default:
+			return Constraints{}, errors.New("unknown constraint `" + key + "`")
+		}
+		if err != nil {
+			return Constraints{}, err
+		}
+	}
+
+	if res.Min != nil && res.Max != nil && *res.Min > *res.Max {
+		return Constraints{}, errors.New("min constraint is greater than max")
+	}
+	if res.MinLength != nil && res.MaxLength != nil && *res.MinLength > *res.MaxLength {
+		return Constraints{}, errors.New("min_length constraint is greater than max_length")
+	}
+	if res.MinItems != nil && res.MaxItems != nil && *res.MinItems > *res.MaxItems {
+		return Constraints{}, errors.New("min_items constraint is greater than max_items")
+	}
+
+	return res, nil
+}
+
+// CheckConstraints checks if constraints are applicable to the type and the default value (if set) satisfies them
+func CheckConstraints(c Constraints, t Type, isArray bool, def string) error {
+	if c.HasItems() && !isArray {
+		return errors.New("items constraints are allowed for arrays only")
+	}
+	if !c.HasValue() {
+		return nil
+	}
+	if t.IsRef() {
+		return errors.New("value constraints are not allowed for custom types")
+	}
+	if (c.Min != nil || c.Max != nil) && !t.IsNumeric() {
+		return errors.New("min/max constraints are allowed for numeric types only")
+	}
+	if (c.MinLength != nil || c.MaxLength != nil || c.Pattern != "") && !t.IsText() {
+		return errors.New("length/pattern constraints are allowed for strings only")
+	}
+	if def != "" {
+		if err := checkValue(c, t, def); err != nil {
+			return errors.New("incorrect default value: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// checkValue checks that the value of the type satisfies value constraints
+func checkValue(c Constraints, t Type, val string) error {
+	if t.IsNumeric() {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return errors.New("should be " + string(t))
+		}
+		if c.Min != nil && (f < *c.Min || c.ExclusiveMin && f == *c.Min) {
+			return errors.New("should be greater than " + formatBound(*c.Min, !c.ExclusiveMin))
+		}
+		if c.Max != nil && (f > *c.Max || c.ExclusiveMax && f == *c.Max) {
+			return errors.New("should be less than " + formatBound(*c.Max, !c.ExclusiveMax))
+		}
+		return nil
+	}
+
+	length := int64(utf8.RuneCountInString(val))
+	if c.MinLength != nil && length < *c.MinLength {
+		return errors.New("length should be at least " + strconv.FormatInt(*c.MinLength, 10))
+	}
+	if c.MaxLength != nil && length > *c.MaxLength {
+		return errors.New("length should be at most " + strconv.FormatInt(*c.MaxLength, 10))
+	}
+	if c.Pattern != "" && !regexp.MustCompile(c.Pattern).MatchString(val) {
+		return errors.New("should match pattern `" + c.Pattern + "`")
+	}
+	return nil
+}
+
+func formatBound(f float64, inclusive bool) string {
+	res := strconv.FormatFloat(f, 'f', -1, 64)
+	if inclusive {
+		res = "or equal to " + res
+	}
+	return res
+}
+
+// splitList splits comma separated list ignoring commas inside brackets and quotes
+func splitList(val string) []string {
+	res := []string{}
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range val {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			res = append(res, val[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, val[start:])
+}
+
+func unquote(val string) string {
+	if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+		return val[1 : len(val)-1]
+	}
+	return val
+}
+
+func parseFloat(key, val string) (*float64, error) {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil, errors.New("constraint `" + key + "` should be a number")
+	}
+	return &f, nil
+}
+
+func parseInt(key, val string) (*int64, error) {
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || i < 0 {
+		return nil, errors.New("constraint `" + key + "` should be a non-negative integer")
+	}
+	return &i, nil
+}
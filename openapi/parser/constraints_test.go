@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConstraints(t *testing.T) {
+	c, err := ParseConstraints("min=1, max=100,exclusive_max=200, min_length=2,max_length=10,pattern='^[a-z,]+$',min_items=1,max_items=5,unique_items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *c.Min != 1 || *c.Max != 200 || c.ExclusiveMin || !c.ExclusiveMax {
+		t.Errorf("got bounds %v %v %v %v", *c.Min, *c.Max, c.ExclusiveMin, c.ExclusiveMax)
+	}
+	if *c.MinLength != 2 || *c.MaxLength != 10 || c.Pattern != "^[a-z,]+$" {
+		t.Errorf("got length %d..%d, pattern %q", *c.MinLength, *c.MaxLength, c.Pattern)
+	}
+	if *c.MinItems != 1 || *c.MaxItems != 5 || !c.UniqueItems {
+		t.Errorf("got items %d..%d, unique %v", *c.MinItems, *c.MaxItems, c.UniqueItems)
+	}
+
+	if c, err := ParseConstraints("unique_items=false"); err != nil || c.UniqueItems || !c.IsEmpty() {
+		t.Errorf("got %+v (%v), want empty constraints", c, err)
+	}
+}
+
+func TestParseConstraintsErrors(t *testing.T) {
+	for val, want := range map[string]string{
+		"min":                       "constraint `min` should have a value",
+		"min=a":                     "constraint `min` should be a number",
+		"min_length=-1":             "should be a non-negative integer",
+		"pattern=[":                 "incorrect pattern",
+		"unique_items=1a":           "should be a boolean",
+		"size=1":                    "unknown constraint `size`",
+		"min=10,max=1":              "min constraint is greater than max",
+		"min_length=3,max_length=2": "min_length constraint is greater than max_length",
+		"min_items=3,max_items=2":   "min_items constraint is greater than max_items",
+	} {
+		if _, err := ParseConstraints(val); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %q", val, err, want)
+		}
+	}
+}
+
+func TestCheckConstraints(t *testing.T) {
+	tests := []struct {
+		val     string
+		t       Type
+		isArray bool
+		def     string
+		wantErr string
+	}{
+		{val: "min=1,max=10", t: TypeInt32, def: "10"},
+		{val: "max_length=3,pattern=^[a-z]+$", t: TypeString, def: "abc"},
+		{val: "max_length=2", t: TypeString, def: "яя"},
+		{val: "min_items=1", t: TypeInt32, isArray: true},
+		{val: "min_items=1", t: TypeInt32, wantErr: "arrays only"},
+		{val: "min=1", t: "$User", wantErr: "not allowed for custom types"},
+		{val: "min=1", t: TypeString, wantErr: "numeric types only"},
+		{val: "pattern=a", t: TypeInt32, wantErr: "strings only"},
+		{val: "exclusive_min=1", t: TypeDouble, def: "1", wantErr: "should be greater than 1"},
+		{val: "max=1", t: TypeDouble, def: "1.5", wantErr: "should be less than or equal to 1"},
+		{val: "min_length=2", t: TypeEmail, def: "a", wantErr: "length should be at least 2"},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = CheckConstraints(c, tt.t, tt.isArray, tt.def)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s %s = %s: got error %v, want %q", tt.t, tt.val, tt.def, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(`a,b(c,d),"e,f",[g,h],'i,j'`)
+	want := []string{"a", "b(c,d)", `"e,f"`, "[g,h]", "'i,j'"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
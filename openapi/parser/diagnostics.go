@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes of diagnostics
+const (
+	CodeSyntax       = "syntax"
+	CodeInvalid      = "invalid"
+	CodeImport       = "import"
+	CodeDuplicate    = "duplicate"
+	CodeGeneric      = "generic"
+	CodeUnknownType  = "unknown-type"
+	CodeUnknownEmbed = "unknown-embed"
+	CodeEmbed        = "embed"
+	CodeCircular     = "circular-embed"
+	CodeUnion        = "union"
+	CodeSecurity     = "security"
+	CodeExample      = "example"
+	CodeOgen         = "ogen"
+)
+
+// Pos is a position of the declaration in the source file
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Diagnostic is a problem found in the document, zero line means that position is unknown
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+func (d *Diagnostic) Error() string {
+	msg := d.Message
+	if d.Line != 0 {
+		msg = fmt.Sprintf("%s (Line: %d, Column: %d)", msg, d.Line, d.Column)
+	}
+	if d.File != "" {
+		msg = d.File + ": " + msg
+	}
+	return msg
+}
+
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, 0, len(d))
+	for i := range d {
+		lines = append(lines, d[i].Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d Diagnostics) HasErrors() bool {
+	for _, v := range d {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns errors of the list as a single error, warnings are skipped
+func (d Diagnostics) Err() error {
+	errs := Diagnostics{}
+	for _, v := range d {
+		if v.Severity == SeverityError {
+			errs = append(errs, v)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return &errs[0]
+	default:
+		return errs
+	}
+}
+
+func (d *Diagnostics) add(err error) {
+	*d = append(*d, ToDiagnostics(err)...)
+}
+
+// ToDiagnostics converts error to the list of diagnostics, plain errors have `invalid` code
+func ToDiagnostics(err error) Diagnostics {
+	if err == nil {
+		return nil
+	}
+	var list Diagnostics
+	if errors.As(err, &list) {
+		return append(Diagnostics{}, list...)
+	}
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return Diagnostics{*d}
+	}
+	return Diagnostics{{Severity: SeverityError, Code: CodeInvalid, Message: err.Error()}}
+}
+
+// ErrCode returns error diagnostic with the code at the node position
+func ErrCode(n *yaml.Node, code string, msg string) error {
+	d := &Diagnostic{Severity: SeverityError, Code: code, Message: msg}
+	if n != nil {
+		d.Line = n.Line
+		d.Column = n.Column
+	}
+	return d
+}
+
+var yamlErrRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// syntaxErr converts YAML error to diagnostic with the line of the error
+func syntaxErr(err error) error {
+	d := &Diagnostic{Severity: SeverityError, Code: CodeSyntax, Message: err.Error()}
+	if m := yamlErrRe.FindStringSubmatch(err.Error()); m != nil {
+		d.Line, _ = strconv.Atoi(m[1])
+		d.Message = m[2]
+	}
+	return d
+}
+
+// atPos sets position to the diagnostics of the error which don't have it
+func atPos(err error, pos Pos) error {
+	list := ToDiagnostics(err)
+	for i := range list {
+		if list[i].File == "" {
+			list[i].File = pos.File
+		}
+		if list[i].Line == 0 {
+			list[i].Line = pos.Line
+			list[i].Column = pos.Column
+		}
+	}
+	return list.Err()
+}
+
+// setFile sets file of the schema and its fields
+func setFile(s *Schema, path string) {
+	s.Pos.File = path
+	for i := range s.Fields {
+		setFile(&s.Fields[i], path)
+	}
+}
+
+// setMethodFile sets file of the method and its schemas
+func setMethodFile(m *Method, path string) {
+	m.Pos.File = path
+	for _, s := range methodSchemas(m) {
+		setFile(s, path)
+	}
+}
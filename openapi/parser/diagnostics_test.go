@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDiagnosticsErr(t *testing.T) {
+	warning := Diagnostic{Severity: SeverityWarning, Code: CodeEmbed, Message: "w"}
+	first := Diagnostic{File: "a.yml", Line: 2, Column: 3, Severity: SeverityError, Code: CodeInvalid, Message: "first"}
+	second := Diagnostic{Severity: SeverityError, Code: CodeUnknownType, Message: "second"}
+
+	if err := (Diagnostics{warning}).Err(); err != nil {
+		t.Errorf("got %v, want no error for warnings", err)
+	}
+	if err := (Diagnostics{warning, first}).Err(); err == nil || err.Error() != "a.yml: first (Line: 2, Column: 3)" {
+		t.Errorf("got %v, want a single error", err)
+	}
+	err := (Diagnostics{first, warning, second}).Err()
+	if err == nil || err.Error() != "a.yml: first (Line: 2, Column: 3)\nsecond" {
+		t.Errorf("got %v, want errors without warnings", err)
+	}
+
+	// Diagnostics are kept through wrapping
+	if diags := ToDiagnostics(err); len(diags) != 2 || diags[1] != second {
+		t.Errorf("got %v, want both errors", diags)
+	}
+	if diags := ToDiagnostics(errors.New("plain")); len(diags) != 1 || diags[0].Code != CodeInvalid || diags[0].Severity != SeverityError {
+		t.Errorf("got %v, want invalid error", diags)
+	}
+}
+
+func TestDiagnosticJSON(t *testing.T) {
+	data, err := json.Marshal(Diagnostics{{Severity: SeverityWarning, Code: CodeExample, Message: "m", Line: 1, Column: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"line":1,"column":2,"severity":"warning","code":"example","message":"m"}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestSyntaxErr(t *testing.T) {
+	_, diags := Diagnose("api.yml", []byte("schemas:\n  User:\n\tid: int32\n"))
+	if len(diags) != 1 || diags[0].Code != CodeSyntax || diags[0].Line != 3 || diags[0].File != "api.yml" {
+		t.Errorf("got %+v, want syntax error at line 3", diags)
+	}
+}
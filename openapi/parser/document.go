@@ -2,22 +2,24 @@ package parser
 
 import (
 	"errors"
+	"os"
+	"sort"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 type Document struct {
 	Settings Settings
 	API      API
 	Schemas  []Schema
+	Generics []Generic
 }
 
 type Settings struct {
-	URL     string
-	Version string
-	Title   string
-	// TODO Securities
+	URL             string
+	Version         string
+	Title           string
+	Security        []SecurityRequirement
+	SecuritySchemes []SecurityScheme
 }
 
 type API struct {
@@ -34,50 +36,101 @@ type Method struct {
 	Method      string
 	Path        string
 	Description string
+	Deprecated  bool
 	Tag         string
 	Name        string
+	Security    []SecurityRequirement
 	Request     Request
 	Response    Response
+	Pos         Pos
 }
 
 type Request struct {
 	Params  []Schema
 	Query   []Schema
 	Headers []Schema
+	Cookies []Schema
 	Body    *Schema
 }
 
 type Response struct {
+	Code    string // Status code of the success response, 200 if empty
+	Headers []Schema
 	Body    *Schema
 	Default *Schema
-	Errors  map[string]*Schema
+	Codes   map[string]*Schema // Other responses by status code, nil schema is an empty response
+}
+
+// SortedCodes returns sorted status codes of other responses
+func (r *Response) SortedCodes() []string {
+	codes := make([]string, 0, len(r.Codes))
+	for code := range r.Codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
 }
 
 type Schema struct {
-	Name        string
-	Type        Type
-	Format      string
-	IsArray     bool
-	Optional    bool
-	Description string
-	Example     string
-	Embeds      []Type
-	Fields      []Schema
+	Name          string
+	Type          Type
+	Format        string
+	IsArray       bool
+	IsMap         bool
+	Optional      bool
+	Nullable      bool
+	Enum          []string
+	Constraints   Constraints
+	Default       string
+	ContentTypes  []string
+	Description   string
+	Example       string
+	Deprecated    bool
+	Embeds        []Type
+	Operators     []Operator
+	Fields        []Schema
+	Variants      []Type
+	Discriminator Discriminator
+	Pos           Pos
 }
 
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeForm        = "application/x-www-form-urlencoded"
+	ContentTypeMultipart   = "multipart/form-data"
+	ContentTypeText        = "text/plain"
+	ContentTypeCSV         = "text/csv"
+	ContentTypeXML         = "application/xml"
+	ContentTypeNDJSON      = "application/x-ndjson"
+	ContentTypeEventStream = "text/event-stream"
+)
+
 type Type string
 
 const (
-	TypeAny    Type = "any"
-	TypeBool   Type = "bool"
-	TypeObject Type = "object"
-	TypeInt32  Type = "int32"
-	TypeInt64  Type = "int64"
-	TypeFloat  Type = "float"
-	TypeDouble Type = "double"
-	TypeString Type = "string"
-	TypeUUID   Type = "uuid"
-	TypeFile   Type = "file"
+	TypeAny      Type = "any"
+	TypeBool     Type = "bool"
+	TypeObject   Type = "object"
+	TypeInt32    Type = "int32"
+	TypeInt64    Type = "int64"
+	TypeFloat    Type = "float"
+	TypeDouble   Type = "double"
+	TypeString   Type = "string"
+	TypeUUID     Type = "uuid"
+	TypeDate     Type = "date"
+	TypeDateTime Type = "datetime"
+	TypeTime     Type = "time"
+	TypeDuration Type = "duration"
+	TypeEmail    Type = "email"
+	TypeURI      Type = "uri"
+	TypeIPv4     Type = "ipv4"
+	TypeIPv6     Type = "ipv6"
+	TypeByte     Type = "byte"
+	TypeDecimal  Type = "decimal"
+	TypeFile     Type = "file"
+	TypeEnum     Type = "enum"
+	TypeOneOf    Type = "oneOf"
+	TypeAnyOf    Type = "anyOf"
 )
 
 func GetType(val string) (Type, error) {
@@ -102,8 +155,30 @@ func GetType(val string) (Type, error) {
 		return TypeString, nil
 	case TypeUUID:
 		return TypeUUID, nil
+	case TypeDate:
+		return TypeDate, nil
+	case TypeDateTime, "date-time":
+		return TypeDateTime, nil
+	case TypeTime:
+		return TypeTime, nil
+	case TypeDuration:
+		return TypeDuration, nil
+	case TypeEmail:
+		return TypeEmail, nil
+	case TypeURI, "url":
+		return TypeURI, nil
+	case TypeIPv4:
+		return TypeIPv4, nil
+	case TypeIPv6:
+		return TypeIPv6, nil
+	case TypeByte, "base64":
+		return TypeByte, nil
+	case TypeDecimal:
+		return TypeDecimal, nil
 	case TypeFile:
 		return TypeFile, nil
+	case TypeEnum:
+		return TypeEnum, nil
 	default:
 		return Type(""), errors.New("unknown scalar type")
 	}
@@ -120,51 +195,104 @@ func (t *Type) IsRef() bool {
 	return strings.HasPrefix(string(*t), "$")
 }
 
-func ParseDocument(data []byte) (Document, error) {
-	var base yaml.Node
-	var d = &base
-	err := yaml.Unmarshal([]byte(data), d)
-	if err != nil {
-		return Document{}, Err(nil, err.Error())
+func (t *Type) IsUnion() bool {
+	return *t == TypeOneOf || *t == TypeAnyOf
+}
+
+// IsText returns true for string types allowing length and pattern constraints
+func (t *Type) IsText() bool {
+	switch *t {
+	case TypeString, TypeEmail, TypeURI:
+		return true
+	default:
+		return false
 	}
+}
 
-	if d.Kind != yaml.DocumentNode || len(d.Content) != 1 {
-		return Document{}, Err(d, "should be document top level")
+func (t *Type) IsNumeric() bool {
+	switch *t {
+	case TypeInt32, TypeInt64, TypeFloat, TypeDouble:
+		return true
+	default:
+		return false
 	}
+}
 
-	d = d.Content[0]
+// ParseDocument parses document, imports are resolved relative to the working directory
+func ParseDocument(data []byte) (Document, error) {
+	return ParseDocumentFile("", data)
+}
 
-	doc := Document{}
+// ParseFile reads and parses document with all its imports
+func ParseFile(path string) (Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	return ParseDocumentFile(path, data)
+}
 
-	top, err := PairNodes(d)
+// DiagnoseFile reads and diagnoses document with all its imports
+func DiagnoseFile(path string) (Document, Diagnostics) {
+	data, err := os.ReadFile(path)
 	if err != nil {
+		return Document{}, Diagnostics{{File: path, Severity: SeverityError, Code: CodeImport, Message: err.Error()}}
+	}
+	return Diagnose(path, data)
+}
+
+// ParseDocumentFile parses document data located at the path, imports are resolved relative to it
+func ParseDocumentFile(path string, data []byte) (Document, error) {
+	doc, diags := Diagnose(path, data)
+	if err := diags.Err(); err != nil {
 		return Document{}, err
 	}
-	for _, p := range top {
-		switch p.Left.Value {
-		case "settings":
-			doc.Settings, err = ParseSettings(p.Right)
-			if err != nil {
-				return Document{}, err
-			}
-		case "api":
-			doc.API, err = ParseAPI(p.Right)
-			if err != nil {
-				return Document{}, err
-			}
-		case "schemas":
-			doc.Schemas, err = ParseSchemas(p.Right)
-			if err != nil {
-				return Document{}, err
-			}
+	return doc, nil
+}
+
+// Diagnose parses document like ParseDocumentFile, but returns all found errors and warnings,
+// document is empty if there are errors. Sections which are parsed successfully are validated
+// even if other sections have errors, so all problems are reported at once
+func Diagnose(path string, data []byte) (Document, Diagnostics) {
+	l := &loader{visited: map[string]struct{}{}}
+	if l.load(path, data); len(l.files) == 0 {
+		return Document{}, l.errs
+	}
+	diags := l.errs
+
+	doc, err := l.merge()
+	diags = append(diags, ToDiagnostics(err)...)
+
+	// References can't be checked without schemas
+	if l.invalidSchemas() {
+		return Document{}, diags
+	}
+
+	// Instances of generics are required to validate references to them
+	if err := InstantiateGenerics(&doc); err != nil {
+		return Document{}, append(diags, ToDiagnostics(err)...)
+	}
+
+	// Security requirements are not checked when security schemes failed to parse
+	for _, d := range Validate(&doc) {
+		if d.Code == CodeSecurity && l.invalidSettings() {
+			continue
 		}
+		diags = append(diags, d)
+	}
+	if diags.HasErrors() {
+		return Document{}, diags
 	}
 
 	if err := ResolveEmbeds(&doc); err != nil {
-		return Document{}, err
+		return Document{}, append(diags, ToDiagnostics(err)...)
 	}
 
-	return doc, nil
+	if diags = append(diags, CheckUnions(&doc)...); diags.HasErrors() {
+		return Document{}, diags
+	}
+
+	return doc, diags
 }
 
 func ResolveEmbeds(doc *Document) error {
@@ -183,40 +311,73 @@ func ResolveEmbeds(doc *Document) error {
 
 	// Resolve schemas first
 	resolved := map[Type]struct{}{}
-	for i := 0; i < len(doc.Schemas); i++ {
-		resolveEmbeds(&doc.Schemas[i], names, &resolved)
+	for _, s := range doc.Schemas {
+		if err := resolveSchema(Type("$"+s.Name), names, &resolved); err != nil {
+			return err
+		}
 	}
 
 	// Resolve methods
 	for i := 0; i < len(doc.API.Methods); i++ {
-		resolveMethodEmbeds(&doc.API.Methods[i], names, &resolved)
+		if err := resolveMethodEmbeds(&doc.API.Methods[i], names, &resolved); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func resolveMethodEmbeds(m *Method, names map[Type]*Schema, resolved *map[Type]struct{}) {
+func resolveMethodEmbeds(m *Method, names map[Type]*Schema, resolved *map[Type]struct{}) error {
 	// Resolve request embeds
 	for i := 0; i < len(m.Request.Params); i++ {
-		resolveEmbeds(&m.Request.Params[i], names, resolved)
+		if err := resolveEmbeds(&m.Request.Params[i], names, resolved); err != nil {
+			return err
+		}
 	}
 	for i := 0; i < len(m.Request.Query); i++ {
-		resolveEmbeds(&m.Request.Query[i], names, resolved)
+		if err := resolveEmbeds(&m.Request.Query[i], names, resolved); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(m.Request.Headers); i++ {
+		if err := resolveEmbeds(&m.Request.Headers[i], names, resolved); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(m.Request.Cookies); i++ {
+		if err := resolveEmbeds(&m.Request.Cookies[i], names, resolved); err != nil {
+			return err
+		}
 	}
 	if m.Request.Body != nil {
-		resolveEmbeds(m.Request.Body, names, resolved)
+		if err := resolveEmbeds(m.Request.Body, names, resolved); err != nil {
+			return err
+		}
 	}
 
 	// Resolve response embeds
+	for i := 0; i < len(m.Response.Headers); i++ {
+		if err := resolveEmbeds(&m.Response.Headers[i], names, resolved); err != nil {
+			return err
+		}
+	}
 	if m.Response.Body != nil {
-		resolveEmbeds(m.Response.Body, names, resolved)
+		if err := resolveEmbeds(m.Response.Body, names, resolved); err != nil {
+			return err
+		}
 	}
 	if m.Response.Default != nil {
-		resolveEmbeds(m.Response.Default, names, resolved)
+		if err := resolveEmbeds(m.Response.Default, names, resolved); err != nil {
+			return err
+		}
 	}
-	for _, v := range m.Response.Errors {
-		if v != nil {
-			resolveEmbeds(v, names, resolved)
+	for _, code := range m.Response.SortedCodes() {
+		if v := m.Response.Codes[code]; v != nil {
+			if err := resolveEmbeds(v, names, resolved); err != nil {
+				return err
+			}
 		}
 	}
+
+	return nil
 }
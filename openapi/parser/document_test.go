@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	doc, diags := Diagnose("", []byte(`
+api:
+  users:
+    GET /users:
+      response:
+        body: $User[]
+    POST /users:
+      security: [oauth]
+      request:
+        body: $Broken
+    GET /users/{id}:
+      response:
+        body: $Unknown
+schemas:
+  Broken:
+    id: foo
+  User:
+    id: int32{min=1}? = 0
+  Account:
+    status: $Unknown
+    age: int32 # Age (abc)
+`))
+	if len(doc.Schemas) != 0 {
+		t.Errorf("got %d schemas, want empty document on errors", len(doc.Schemas))
+	}
+
+	// Parse errors don't hide validation of other declarations,
+	// references to schemas with errors are not reported as unknown
+	want := []string{
+		"error invalid 16: unknown scalar type",
+		"error invalid 18: incorrect default value: should be greater than or equal to 1",
+		"error unknown-type 20: type `$Unknown` is not found",
+		"warning example 21: incorrect example is skipped: should be int32",
+		"error security 7: security scheme `oauth` is not found (method `POST /users`)",
+		"error unknown-type 13: type `$Unknown` is not found",
+	}
+	got := []string{}
+	for _, d := range diags {
+		got = append(got, string(d.Severity)+" "+d.Code+" "+strconv.Itoa(d.Line)+": "+d.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiagnoseInvalidSections(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "security schemes are unknown",
+			data: `
+settings:
+  security_schemes: 1
+api:
+  users:
+    GET /users:
+      security: [oauth]
+`,
+			want: []string{CodeInvalid},
+		},
+		{
+			name: "schemas are unknown",
+			data: `
+api:
+  users:
+    GET /users:
+      response:
+        body: $User
+schemas: [User]
+`,
+			want: []string{CodeInvalid},
+		},
+		{
+			name: "duplicates are validated",
+			data: `
+schemas:
+  User:
+    id: $Unknown
+  User:
+    id: int32
+`,
+			want: []string{CodeDuplicate, CodeUnknownType},
+		},
+		{
+			name: "syntax",
+			data: "schemas: [",
+			want: []string{CodeSyntax},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := Diagnose("", []byte(tt.data))
+			codes := []string{}
+			for _, d := range diags {
+				codes = append(codes, d.Code)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got codes %v, want %v:\n%s", codes, tt.want, diags)
+			}
+		})
+	}
+}
+
+// Incorrect declarations are reported as diagnostics, they don't reach later steps
+func TestDiagnoseNoPanic(t *testing.T) {
+	inputs := []string{
+		"",
+		"1",
+		"[]",
+		"api: 1",
+		"api: {users: {GET /users: 1}}",
+		"api: {users: {GET /users: {request: {body: $A}}}}",
+		"schemas: {A<$B>: {}, B<$A>: {}}",
+		"schemas: {A<$B>: {}}",
+		"schemas:\n  A: $Page[$A]\n  Page[T]:\n    items: T[]",
+		"schemas:\n  Page[T]:\n    items: $Page[$Page[T]]\n  A: $Page[int32]",
+		"schemas:\n  A: oneOf[type]($B,$C)\n  B: {}\n  C: int32",
+		"schemas:\n  A: Omit($B, id)\n  B: {name: string}",
+		"schemas:\n  A: Omit($A, id)",
+		"schemas: {A: {_type: {x: int32}, example: '{\"x\": 1}'}}",
+		"imports: [not-found.yml]",
+	}
+	for _, data := range inputs {
+		Diagnose("", []byte(data))
+	}
+}
@@ -0,0 +1,328 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Generic is a parameterised schema, e.g. `Page[T]`,
+// it is instantiated at use sites as `$Page[$User]` into a concrete schema `PageUser`
+type Generic struct {
+	Name   string
+	Params []string
+	Path   string
+	Node   NodePair
+}
+
+// maxInstances limits number of generic instances to stop infinite expansion, e.g. `A[T]: {a: $A[$A[T]]}`
+const maxInstances = 1000
+
+var paramRe = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// ParseGeneric parses generic schema declaration, e.g. `Page[T]` or `Pair[K, V]<$Base>`,
+// false is returned for regular schemas
+func ParseGeneric(p *NodePair) (Generic, bool, error) {
+	def := p.Left.Value
+	if lt := strings.Index(def, "<"); lt >= 0 {
+		def = def[:lt]
+	}
+	def = strings.TrimSpace(def)
+	lb := strings.Index(def, "[")
+	if lb < 0 {
+		return Generic{}, false, nil
+	}
+	if lb == 0 || !strings.HasSuffix(def, "]") {
+		return Generic{}, false, Err(p.Left, "incorrect generic schema declaration, e.g. `Page[T]`")
+	}
+
+	g := Generic{Name: def[:lb], Node: *p}
+	unique := map[string]struct{}{}
+	for _, param := range strings.Split(def[lb+1:len(def)-1], ",") {
+		param = strings.TrimSpace(param)
+		if !paramRe.MatchString(param) {
+			return Generic{}, false, Err(p.Left, "incorrect type parameter `"+param+"`")
+		}
+		if _, err := GetType(param); err == nil {
+			return Generic{}, false, Err(p.Left, "type parameter `"+param+"` conflicts with scalar type")
+		}
+		if _, ok := unique[param]; ok {
+			return Generic{}, false, Err(p.Left, "duplicate type parameter `"+param+"`")
+		}
+		unique[param] = struct{}{}
+		g.Params = append(g.Params, param)
+	}
+
+	return g, true, nil
+}
+
+// InstantiateGenerics replaces generic types with concrete schemas, e.g. `$Page[$User]` with `$PageUser`,
+// instances are added to the document schemas
+func InstantiateGenerics(doc *Document) error {
+	inst := &instantiator{
+		generics:  map[string]*Generic{},
+		declared:  map[string]struct{}{},
+		instances: map[string]string{},
+	}
+	for i := range doc.Generics {
+		inst.generics[doc.Generics[i].Name] = &doc.Generics[i]
+	}
+	for _, s := range doc.Schemas {
+		inst.declared[s.Name] = struct{}{}
+	}
+
+	// Instances are appended after each walk, so pointers to schemas stay valid
+	walkSchemas := func(from int) (int, error) {
+		i := from
+		for ; i < len(doc.Schemas); i++ {
+			if err := inst.walk(&doc.Schemas[i]); err != nil {
+				return 0, err
+			}
+			doc.Schemas = append(doc.Schemas, inst.flush()...)
+		}
+		return i, nil
+	}
+
+	done, err := walkSchemas(0)
+	if err != nil {
+		return err
+	}
+
+	for i := range doc.API.Methods {
+		for _, s := range methodSchemas(&doc.API.Methods[i]) {
+			if err := inst.walk(s); err != nil {
+				return err
+			}
+		}
+		doc.Schemas = append(doc.Schemas, inst.flush()...)
+	}
+
+	_, err = walkSchemas(done)
+	return err
+}
+
+// methodSchemas returns all schemas of the method
+func methodSchemas(m *Method) []*Schema {
+	res := []*Schema{}
+	lists := [][]Schema{m.Request.Params, m.Request.Query, m.Request.Headers, m.Request.Cookies, m.Response.Headers}
+	for _, l := range lists {
+		for i := range l {
+			res = append(res, &l[i])
+		}
+	}
+	for _, s := range []*Schema{m.Request.Body, m.Response.Body, m.Response.Default} {
+		if s != nil {
+			res = append(res, s)
+		}
+	}
+	for _, code := range m.Response.SortedCodes() {
+		if s := m.Response.Codes[code]; s != nil {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+type instantiator struct {
+	generics  map[string]*Generic
+	declared  map[string]struct{}
+	instances map[string]string // Canonical generic types by names of instances, e.g. `$Page[$User]` for `PageUser`
+	pending   []Schema
+}
+
+func (inst *instantiator) flush() []Schema {
+	res := inst.pending
+	inst.pending = nil
+	return res
+}
+
+// walk resolves generic types of the schema and its fields, errors without position get position of the schema
+func (inst *instantiator) walk(s *Schema) error {
+	if err := inst.walkTypes(s); err != nil {
+		return atPos(err, s.Pos)
+	}
+	for i := range s.Fields {
+		if err := inst.walk(&s.Fields[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (inst *instantiator) walkTypes(s *Schema) error {
+	var err error
+	if s.Type, err = inst.resolve(s.Type); err != nil {
+		return err
+	}
+	for i := range s.Embeds {
+		if s.Embeds[i], err = inst.resolve(s.Embeds[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.Variants {
+		if s.Variants[i], err = inst.resolve(s.Variants[i]); err != nil {
+			return err
+		}
+	}
+	for i := range s.Discriminator.Mapping {
+		if s.Discriminator.Mapping[i].Type, err = inst.resolve(s.Discriminator.Mapping[i].Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns type of the concrete schema for generic type, other types are returned as is
+func (inst *instantiator) resolve(t Type) (Type, error) {
+	val := string(t)
+	lb := strings.Index(val, "[")
+	if !t.IsRef() || lb < 0 || !strings.HasSuffix(val, "]") {
+		return t, nil
+	}
+
+	name := val[1:lb]
+	g, ok := inst.generics[name]
+	if !ok {
+		return "", ErrCode(nil, CodeGeneric, "generic schema `"+name+"` is not found")
+	}
+	args := splitList(val[lb+1 : len(val)-1])
+	if len(args) != len(g.Params) {
+		return "", ErrCode(nil, CodeGeneric, fmt.Sprintf("generic schema `%s` expects %d type parameter(s), got `%s`", name, len(g.Params), val))
+	}
+
+	concrete := name
+	canonical := make([]string, 0, len(args))
+	values := map[string]string{}
+	for i, a := range args {
+		arg := Type(strings.TrimSpace(a))
+		if arg.IsRef() {
+			var err error
+			if arg, err = inst.resolve(arg); err != nil {
+				return "", err
+			}
+			concrete += arg.Name()
+			canonical = append(canonical, string(arg))
+		} else {
+			typ, err := GetType(string(arg))
+			if err != nil || typ == TypeEnum || arg == "" {
+				return "", ErrCode(nil, CodeGeneric, fmt.Sprintf("incorrect type argument `%s` of `%s`", arg, val))
+			}
+			concrete += strings.ToUpper(string(typ[:1])) + string(typ[1:])
+			canonical = append(canonical, string(typ))
+		}
+		values[g.Params[i]] = string(arg)
+	}
+
+	// Names are joined from arguments, so different arguments may give the same name, e.g. `$Pair[$A,$BC]` and `$Pair[$AB,$C]`
+	key := "$" + name + "[" + strings.Join(canonical, ",") + "]"
+	if prev, ok := inst.instances[concrete]; ok {
+		if prev != key {
+			return "", ErrCode(nil, CodeGeneric, fmt.Sprintf("instances `%s` and `%s` have the same name `%s`", prev, key, concrete))
+		}
+		return Type("$" + concrete), nil
+	}
+	if _, ok := inst.declared[concrete]; ok {
+		return "", ErrCode(nil, CodeGeneric, fmt.Sprintf("instance of `%s` conflicts with schema `%s`", val, concrete))
+	}
+	if len(inst.instances) >= maxInstances {
+		return "", ErrCode(nil, CodeGeneric, "too many generic instances, check recursion of `"+name+"`")
+	}
+	inst.instances[concrete] = key
+
+	left := *g.Node.Left
+	left.Value = concrete
+	if lt := strings.Index(g.Node.Left.Value, "<"); lt >= 0 {
+		embeds, err := replaceParams(g.Node.Left, g.Node.Left.Value[lt:], values)
+		if err != nil {
+			return "", fileErr(g.Path, err)
+		}
+		left.Value += embeds
+	}
+	right, err := substituteParams(g.Node.Right, values)
+	if err != nil {
+		return "", fileErr(g.Path, err)
+	}
+
+	s, err := ParseSchema(&NodePair{Left: &left, Right: right})
+	if err != nil {
+		return "", fileErr(g.Path, err)
+	}
+	setFile(&s, g.Path)
+	inst.pending = append(inst.pending, s)
+
+	return Type("$" + concrete), nil
+}
+
+// substituteParams copies the node replacing type parameters in values and in embeds of the keys
+func substituteParams(n *yaml.Node, values map[string]string) (*yaml.Node, error) {
+	res := *n
+	if n.Kind == yaml.ScalarNode {
+		var err error
+		res.Value, err = replaceParams(n, n.Value, values)
+		return &res, err
+	}
+	res.Content = make([]*yaml.Node, len(n.Content))
+	for i, c := range n.Content {
+		isKey := n.Kind == yaml.MappingNode && i%2 == 0
+		if !isKey {
+			var err error
+			if res.Content[i], err = substituteParams(c, values); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := *c
+		if lt := strings.Index(c.Value, "<"); lt >= 0 {
+			embeds, err := replaceParams(c, c.Value[lt:], values)
+			if err != nil {
+				return nil, err
+			}
+			key.Value = c.Value[:lt] + embeds
+		}
+		res.Content[i] = &key
+	}
+	return &res, nil
+}
+
+// replaceParams replaces type parameters in the type of the node, quoted strings and default value are kept as is
+func replaceParams(n *yaml.Node, val string, values map[string]string) (string, error) {
+	typ, def, hasDefault := splitDefault(val)
+
+	var sb strings.Builder
+	isTokenRune := func(r byte) bool {
+		return r == '_' || r == '$' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	}
+	for i := 0; i < len(typ); {
+		if q := typ[i]; q == '"' || q == '\'' {
+			j := strings.IndexByte(typ[i+1:], q)
+			if j < 0 {
+				return "", ErrCode(n, CodeGeneric, "unclosed quote in `"+typ+"`")
+			}
+			sb.WriteString(typ[i : i+j+2])
+			i += j + 2
+			continue
+		}
+		if !isTokenRune(typ[i]) {
+			sb.WriteByte(typ[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(typ) && isTokenRune(typ[j]) {
+			j++
+		}
+		if v, ok := values[typ[i:j]]; ok {
+			sb.WriteString(v)
+		} else {
+			sb.WriteString(typ[i:j])
+		}
+		i = j
+	}
+
+	if hasDefault {
+		return sb.String() + " = " + def, nil
+	}
+	return sb.String(), nil
+}
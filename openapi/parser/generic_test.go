@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInstantiateGenerics(t *testing.T) {
+	doc := mustParse(t, `
+api:
+  users:
+    'GET /users':
+      response:
+        body: $Page[$User]
+    'GET /counts':
+      response:
+        body: $Page[int64]
+schemas:
+  Page[T]:
+    items: T[]
+    total: int64
+  Pair[K, V]:
+    key: K
+    value: V
+  Users:
+    first: $Page[$User]
+    pairs: $Pair[$User, $Page[$User]][]
+  User:
+    name: string
+`)
+	names := map[string]*Schema{}
+	for i := range doc.Schemas {
+		names[doc.Schemas[i].Name] = &doc.Schemas[i]
+	}
+	for _, name := range []string{"PageUser", "PageInt64", "PairUserPageUser"} {
+		if names[name] == nil {
+			t.Errorf("instance %s is not found", name)
+		}
+	}
+	if len(doc.Schemas) != 5 {
+		t.Errorf("got %d schemas, want 5 (instances are created once)", len(doc.Schemas))
+	}
+	if items := names["PageUser"].Fields[0]; items.Type != "$User" || !items.IsArray {
+		t.Errorf("got items %s (array %t), want $User[]", items.Type, items.IsArray)
+	}
+	if body := doc.API.Methods[1].Response.Body; body.Type != "$PageInt64" {
+		t.Errorf("got body %s, want $PageInt64", body.Type)
+	}
+}
+
+func TestInstantiateGenericsErrors(t *testing.T) {
+	const generics = `
+  Pair[K, V]:
+    key: K
+    value: V
+  A: {a: string}
+  AB: {a: string}
+  BC: {a: string}
+  C: {a: string}
+`
+	tests := []struct {
+		name  string
+		field string
+		want  string
+	}{
+		{"unknown generic", "$Box[$A]", "generic schema `Box` is not found"},
+		{"wrong number of arguments", "$Pair[$A]", "expects 2 type parameter(s)"},
+		{"incorrect argument", "$Pair[$A, foo]", "incorrect type argument `foo`"},
+		{"same instance name", "$Pair[$A, $BC]\n    b: $Pair[$AB, $C]", "have the same name `PairABC`"},
+		{"conflict with schema", "$Pair[$A, $B]\n  PairAB: {a: string}\n  B: {a: string}", "conflicts with schema `PairAB`"},
+		{"unclosed quote", "$Box[$A]\n  Box[T]:\n    value: T\n    name: string{pattern='abc}", "unclosed quote in `string{pattern='abc}`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDocument([]byte("schemas:\n  X:\n    a: " + tt.field + generics))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
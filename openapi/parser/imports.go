@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// filePart is a parsed part of a document, API level common section is kept separately
+// to be applied after all files are loaded
+type filePart struct {
+	Path     string
+	Settings *Settings
+	API      API
+	Common   *Method
+	Schemas  []Schema
+	Generics []Generic
+	Imports  []string
+
+	// InvalidSettings is set if the settings section has errors, so security schemes are unknown
+	InvalidSettings bool
+	// InvalidSchemas is set if the schemas section is not parsed at all, so all references are unknown
+	InvalidSchemas bool
+}
+
+// parseFilePart parses top level sections of a single file without resolving imports,
+// sections are parsed independently and the part is returned with all found errors
+func parseFilePart(path string, data []byte) (filePart, error) {
+	var base yaml.Node
+	var d = &base
+	err := yaml.Unmarshal(data, d)
+	if err != nil {
+		return filePart{}, syntaxErr(err)
+	}
+
+	if d.Kind != yaml.DocumentNode || len(d.Content) != 1 {
+		return filePart{}, Err(d, "should be document top level")
+	}
+
+	d = d.Content[0]
+
+	file := filePart{Path: path}
+
+	top, err := PairNodes(d)
+	if err != nil {
+		return filePart{}, err
+	}
+	var errs Diagnostics
+	for _, p := range top {
+		switch p.Left.Value {
+		case "settings":
+			settings, err := ParseSettings(p.Right)
+			if err != nil {
+				errs.add(err)
+				file.InvalidSettings = true
+				continue
+			}
+			file.Settings = &settings
+		case "imports":
+			file.Imports, err = ParseImports(p.Right)
+			if err != nil {
+				errs.add(err)
+			}
+		case "api":
+			file.API, file.Common, err = parseAPI(p.Right)
+			if err != nil {
+				errs.add(err)
+			}
+		case "schemas":
+			file.Schemas, file.Generics, err = ParseSchemas(p.Right)
+			if err != nil {
+				errs.add(err)
+				file.InvalidSchemas = file.Schemas == nil
+			}
+			for i := range file.Generics {
+				file.Generics[i].Path = path
+			}
+		}
+	}
+
+	for i := range file.Schemas {
+		setFile(&file.Schemas[i], path)
+	}
+	for i := range file.API.Methods {
+		setMethodFile(&file.API.Methods[i], path)
+	}
+	if file.Common != nil {
+		setMethodFile(file.Common, path)
+	}
+
+	return file, errs.Err()
+}
+
+// ParseImports parses list of imported files, paths are relative and may contain globs
+func ParseImports(n *yaml.Node) ([]string, error) {
+	if n.Kind == yaml.ScalarNode {
+		return []string{strings.TrimSpace(n.Value)}, nil
+	}
+	if n.Kind != yaml.SequenceNode {
+		return nil, Err(n, "imports should be list of files")
+	}
+	imports := []string{}
+	for _, i := range n.Content {
+		if i.Kind != yaml.ScalarNode || strings.TrimSpace(i.Value) == "" {
+			return nil, Err(i, "import should be a file path")
+		}
+		imports = append(imports, strings.TrimSpace(i.Value))
+	}
+	return imports, nil
+}
+
+type loader struct {
+	visited map[string]struct{}
+	files   []filePart
+	errs    Diagnostics
+}
+
+// load parses the file and its imports, errors are collected to continue with other files
+func (l *loader) load(path string, data []byte) {
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			l.errs.add(fileErr(path, err))
+			return
+		}
+		l.visited[abs] = struct{}{}
+	}
+
+	file, err := parseFilePart(path, data)
+	if err != nil {
+		l.errs.add(fileErr(path, err))
+	}
+	if len(l.files) != 0 && file.Settings != nil {
+		l.errs.add(fileErr(path, Err(nil, "settings are allowed in the main file only")))
+	}
+	l.files = append(l.files, file)
+
+	dir := filepath.Dir(path)
+	for _, i := range file.Imports {
+		pattern := i
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			l.errs.add(fileErr(path, ErrCode(nil, CodeImport, fmt.Sprintf("incorrect import `%s`: %s", i, err.Error()))))
+			continue
+		}
+		if len(matches) == 0 {
+			l.errs.add(fileErr(path, ErrCode(nil, CodeImport, fmt.Sprintf("imported file `%s` is not found", i))))
+			continue
+		}
+		for _, m := range matches {
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				l.errs.add(fileErr(path, err))
+				continue
+			}
+			if _, ok := l.visited[abs]; ok {
+				continue
+			}
+			data, err := os.ReadFile(m)
+			if err != nil {
+				l.errs.add(fileErr(path, ErrCode(nil, CodeImport, err.Error())))
+				continue
+			}
+			l.load(m, data)
+		}
+	}
+}
+
+// merge combines loaded files into a document, the first file is the main one,
+// the document is returned with all found errors to validate its correct parts
+func (l *loader) merge() (Document, error) {
+	doc := Document{}
+	root := l.files[0]
+
+	if root.Settings != nil {
+		doc.Settings = *root.Settings
+	} else {
+		fillSecurityDefaults(&doc.Settings)
+	}
+
+	tags := map[string]int{}
+	methods := map[string]string{}
+	schemas := map[string]string{}
+	var errs Diagnostics
+	for i, f := range l.files {
+		if err := ApplyCommon(f.API.Methods, f.Common); err != nil {
+			errs.add(fileErr(f.Path, err))
+		}
+		if i != 0 {
+			if err := ApplyCommon(f.API.Methods, root.Common); err != nil {
+				errs.add(fileErr(f.Path, err))
+			}
+		}
+
+		for _, t := range f.API.Tags {
+			if idx, ok := tags[t.Name]; ok {
+				if doc.API.Tags[idx].Description == "" {
+					doc.API.Tags[idx].Description = t.Description
+				}
+				continue
+			}
+			tags[t.Name] = len(doc.API.Tags)
+			doc.API.Tags = append(doc.API.Tags, t)
+		}
+
+		for _, m := range f.API.Methods {
+			key := m.Method + " " + m.Path
+			if path, ok := methods[key]; ok {
+				errs.add(atPos(ErrCode(nil, CodeDuplicate, "method `"+key+"` is declared twice"+filesSuffix(path, f.Path)), m.Pos))
+				continue
+			}
+			methods[key] = f.Path
+			doc.API.Methods = append(doc.API.Methods, m)
+		}
+
+		for _, s := range f.Schemas {
+			if path, ok := schemas[s.Name]; ok {
+				errs.add(atPos(ErrCode(nil, CodeDuplicate, "schema `"+s.Name+"` is declared twice"+filesSuffix(path, f.Path)), s.Pos))
+				continue
+			}
+			schemas[s.Name] = f.Path
+			doc.Schemas = append(doc.Schemas, s)
+		}
+
+		for _, g := range f.Generics {
+			if path, ok := schemas[g.Name]; ok {
+				pos := Pos{File: g.Path, Line: g.Node.Left.Line, Column: g.Node.Left.Column}
+				errs.add(atPos(ErrCode(nil, CodeDuplicate, "schema `"+g.Name+"` is declared twice"+filesSuffix(path, f.Path)), pos))
+				continue
+			}
+			schemas[g.Name] = f.Path
+			doc.Generics = append(doc.Generics, g)
+		}
+	}
+
+	if err := FillMethodsNames(doc.API.Methods); err != nil {
+		errs.add(err)
+	}
+
+	return doc, errs.Err()
+}
+
+// invalidSettings reports if the main file has settings which failed to parse
+func (l *loader) invalidSettings() bool {
+	return l.files[0].InvalidSettings
+}
+
+// invalidSchemas reports if any file has the schemas section which failed to parse
+func (l *loader) invalidSchemas() bool {
+	for _, f := range l.files {
+		if f.InvalidSchemas {
+			return true
+		}
+	}
+	return false
+}
+
+func fileErr(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return atPos(err, Pos{File: path})
+}
+
+func filesSuffix(a, b string) string {
+	if a == "" && b == "" {
+		return ""
+	}
+	if a == "" {
+		a = "main file"
+	}
+	return fmt.Sprintf(" (%s, %s)", a, b)
+}
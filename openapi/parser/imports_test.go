@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImports(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"api.yml": `
+imports:
+  - users/*.yml
+api:
+  _common:
+    response:
+      default: $Error
+schemas:
+  Error:
+    message: string
+`,
+		"users/api.yml": `
+imports: ../api.yml
+api:
+  _common:
+    request:
+      headers:
+        x-user: string
+  users:
+    GET /users:
+      response:
+        body: $User[]
+`,
+		"users/schemas.yml": `
+schemas:
+  User:
+    id: int64
+`,
+	})
+
+	doc, err := ParseFile(filepath.Join(dir, "api.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Schemas) != 2 || doc.Schemas[0].Name != "Error" || doc.Schemas[1].Name != "User" {
+		t.Fatalf("got schemas %+v, want Error and User", doc.Schemas)
+	}
+	if len(doc.API.Methods) != 1 {
+		t.Fatalf("got %d methods, want 1", len(doc.API.Methods))
+	}
+	// Common sections of the main file and the imported file are applied
+	m := doc.API.Methods[0]
+	if m.Response.Default == nil || m.Response.Default.Type != "$Error" {
+		t.Errorf("got default response %+v, want $Error from the main file", m.Response.Default)
+	}
+	if len(m.Request.Headers) != 1 || m.Request.Headers[0].Name != "X-User" {
+		t.Errorf("got headers %+v, want header of the imported file", m.Request.Headers)
+	}
+}
+
+func TestImportsErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"api.yml": `
+imports: [users.yml, missing.yml, "[", other.yml]
+schemas:
+  User:
+    id: int64
+`,
+		"users.yml": `
+schemas:
+  User:
+    id: int64
+`,
+		"other.yml": `
+settings:
+  title: Other
+api:
+  users:
+    GET /users:
+`,
+	})
+
+	_, diags := DiagnoseFile(filepath.Join(dir, "api.yml"))
+	got := []string{}
+	for _, d := range diags {
+		got = append(got, filepath.Base(d.File)+" "+d.Code+" "+d.Message)
+	}
+	want := []string{
+		"api.yml import imported file `missing.yml` is not found",
+		"api.yml import incorrect import `[`: syntax error in pattern",
+		"other.yml invalid settings are allowed in the main file only",
+		"users.yml duplicate schema `User` is declared twice (" + filepath.Join(dir, "api.yml") + ", " + filepath.Join(dir, "users.yml") + ")",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, diags := DiagnoseFile(filepath.Join(dir, "none.yml")); len(diags) != 1 || diags[0].Code != CodeImport {
+		t.Errorf("got %v, want import error for missing main file", diags)
+	}
+}
@@ -0,0 +1,68 @@
+package parser
+
+import "fmt"
+
+// CheckOgen checks that the document can be generated by ogen, embeds should be resolved:
+// anyOf with object variants is an error, content types of multipart file parts are skipped with a warning
+func CheckOgen(doc *Document) Diagnostics {
+	names := map[string]*Schema{}
+	for i := range doc.Schemas {
+		names[doc.Schemas[i].Name] = &doc.Schemas[i]
+	}
+
+	var diags Diagnostics
+	add := func(pos Pos, severity Severity, msg string) {
+		diags = append(diags, Diagnostic{
+			File:     pos.File,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Severity: severity,
+			Code:     CodeOgen,
+			Message:  msg,
+		})
+	}
+
+	var check func(s *Schema, pos Pos)
+	check = func(s *Schema, pos Pos) {
+		if s.Pos.Line != 0 {
+			pos = s.Pos
+		}
+		for i := range s.Fields {
+			check(&s.Fields[i], pos)
+		}
+		if s.Type != TypeAnyOf {
+			return
+		}
+		for _, v := range s.Variants {
+			if ref, ok := names[v.Name()]; ok && ref.Type == TypeObject && !ref.IsArray && !ref.IsMap {
+				add(pos, SeverityError, fmt.Sprintf("anyOf with object variant `%s` is not supported by ogen, use oneOf or generate specification only", v))
+			}
+		}
+	}
+
+	for i := range doc.Schemas {
+		check(&doc.Schemas[i], Pos{})
+	}
+	for i := range doc.API.Methods {
+		m := &doc.API.Methods[i]
+		for _, s := range methodSchemas(m) {
+			check(s, m.Pos)
+		}
+
+		body := m.Request.Body
+		if body == nil || len(body.ContentTypes) != 1 || body.ContentTypes[0] != ContentTypeMultipart {
+			continue
+		}
+		for _, f := range body.Fields {
+			if f.Type == TypeFile && f.Format != "" {
+				pos := f.Pos
+				if pos.Line == 0 {
+					pos = m.Pos
+				}
+				add(pos, SeverityWarning, "content types of file part `"+f.Name+"` are not supported by ogen, they are skipped")
+			}
+		}
+	}
+
+	return diags
+}
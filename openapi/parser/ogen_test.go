@@ -0,0 +1,42 @@
+package parser
+
+import "testing"
+
+func TestCheckOgen(t *testing.T) {
+	doc := mustParse(t, `
+api:
+  images:
+    'POST /images':
+      request:
+        multipart:
+          image: file(image/png)
+          raw: file
+          meta: $Meta
+schemas:
+  Meta:
+    channel: $Channel
+    value: anyOf(string,int64)
+  Channel: anyOf($Email,$Sms,string)
+  Email:
+    address: string
+  Sms:
+    phone: string
+`)
+	diags := CheckOgen(&doc)
+	want := []struct {
+		line     int
+		severity Severity
+	}{
+		{14, SeverityError},
+		{14, SeverityError},
+		{7, SeverityWarning},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got diagnostics %v, want %d", diags, len(want))
+	}
+	for i, w := range want {
+		if d := diags[i]; d.Line != w.line || d.Severity != w.severity || d.Code != CodeOgen {
+			t.Errorf("got diagnostic %+v, want %s on line %d", d, w.severity, w.line)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type OperatorKind string
+
+const (
+	OperatorPick     OperatorKind = "Pick"
+	OperatorOmit     OperatorKind = "Omit"
+	OperatorPartial  OperatorKind = "Partial"
+	OperatorRequired OperatorKind = "Required"
+)
+
+// Operator transforms fields of the embedded schema
+type Operator struct {
+	Kind   OperatorKind
+	Fields []string
+}
+
+// IsOperator checks that value is a schema operator, e.g. `Omit($User, password)`
+func IsOperator(val string) bool {
+	lp := strings.Index(val, "(")
+	if lp < 0 || !strings.HasSuffix(val, ")") {
+		return false
+	}
+	switch OperatorKind(strings.TrimSpace(val[:lp])) {
+	case OperatorPick, OperatorOmit, OperatorPartial, OperatorRequired:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseOperator parses schema operator and returns transformed type with operators in order of applying,
+// operators can be nested, e.g. `Partial(Omit($User, id))`
+func ParseOperator(val string) (Type, []Operator, error) {
+	lp := strings.Index(val, "(")
+	kind := OperatorKind(strings.TrimSpace(val[:lp]))
+	args := splitList(val[lp+1 : len(val)-1])
+
+	var operators []Operator
+	typ := Type(strings.TrimSpace(args[0]))
+	switch {
+	case IsOperator(string(typ)):
+		var err error
+		typ, operators, err = ParseOperator(string(typ))
+		if err != nil {
+			return "", nil, err
+		}
+	case typ.IsRef():
+	default:
+		return "", nil, fmt.Errorf("first argument of `%s` should be custom type", kind)
+	}
+
+	op := Operator{Kind: kind}
+	for _, a := range args[1:] {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			return "", nil, fmt.Errorf("empty field name in `%s`", kind)
+		}
+		op.Fields = append(op.Fields, a)
+	}
+	switch kind {
+	case OperatorPick, OperatorOmit:
+		if len(op.Fields) == 0 {
+			return "", nil, fmt.Errorf("fields should be specified, e.g. `%s($User, id)`", kind)
+		}
+	default:
+		if len(op.Fields) != 0 {
+			return "", nil, fmt.Errorf("`%s` doesn't accept fields, e.g. `%s($User)`", kind, kind)
+		}
+	}
+
+	return typ, append(operators, op), nil
+}
+
+// ApplyOperators transforms fields, referenced fields should exist
+func ApplyOperators(fields []Schema, operators []Operator) ([]Schema, error) {
+	for _, op := range operators {
+		idx := map[string]int{}
+		for i, f := range fields {
+			idx[f.Name] = i
+		}
+		selected := map[string]struct{}{}
+		for _, name := range op.Fields {
+			if _, ok := idx[name]; !ok {
+				return nil, errors.New("field `" + name + "` of `" + string(op.Kind) + "` is not found")
+			}
+			selected[name] = struct{}{}
+		}
+
+		res := make([]Schema, 0, len(fields))
+		for _, f := range fields {
+			_, ok := selected[f.Name]
+			switch op.Kind {
+			case OperatorPick:
+				if !ok {
+					continue
+				}
+			case OperatorOmit:
+				if ok {
+					continue
+				}
+			case OperatorPartial:
+				f.Optional = true
+			case OperatorRequired:
+				f.Optional = false
+				f.Default = ""
+			}
+			res = append(res, f)
+		}
+		fields = res
+	}
+	return fields, nil
+}
@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	const user = `
+  User:
+    id: int64
+    name: string?
+    password_hash: string
+`
+	tests := []struct {
+		name   string
+		schema string
+		want   []string // Fields with `?` suffix for optional ones
+	}{
+		{"omit", "Public: Omit($User, password_hash)", []string{"id", "name?"}},
+		{"pick", "Public: Pick($User, id, name)", []string{"id", "name?"}},
+		{"partial", "Public: Partial($User)", []string{"id?", "name?", "password_hash?"}},
+		{"required", "Public: Required($User)", []string{"id", "name", "password_hash"}},
+		{"nested", "Public: Partial(Omit($User, password_hash))", []string{"id?", "name?"}},
+		{"embed", "Public<$User>:\n    extra: bool", []string{"id", "name?", "password_hash", "extra"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParse(t, "schemas:\n  "+tt.schema+user)
+			if got := fieldNames(doc.Schemas[0].Fields); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Schemas resolved as dependencies are not resolved again, so removed fields don't come back
+func TestOperatorsOrder(t *testing.T) {
+	const (
+		a      = "  A<$Public>:\n    extra: string\n"
+		public = "  Public: Omit($User, password_hash)\n"
+		user   = "  User:\n    id: int64\n    password_hash: string\n"
+	)
+	for name, schemas := range map[string]string{
+		"dependency first": user + public + a,
+		"dependency last":  a + public + user,
+	} {
+		t.Run(name, func(t *testing.T) {
+			doc := mustParse(t, "schemas:\n"+schemas)
+			for _, s := range doc.Schemas {
+				names := strings.Join(fieldNames(s.Fields), ",")
+				if s.Name != "User" && strings.Contains(names, "password_hash") {
+					t.Errorf("omitted field leaks into %s: %s", s.Name, names)
+				}
+			}
+		})
+	}
+
+	// Common response is shared by methods and resolved for each of them
+	doc := mustParse(t, `
+api:
+  _common:
+    response:
+      default: Omit($Error, debug)
+  users:
+    'GET /a':
+    'GET /b':
+schemas:
+  Error:
+    code: int64
+    debug: string
+`)
+	for _, m := range doc.API.Methods {
+		if got := fieldNames(m.Response.Default.Fields); strings.Join(got, ",") != "code" {
+			t.Errorf("%s %s: got default response fields %v, want [code]", m.Method, m.Path, got)
+		}
+	}
+}
+
+// Embeds shared by sibling branches are not a cycle, e.g. `X` embeds `Base` directly and through `User`
+func TestOperatorsDiamond(t *testing.T) {
+	doc := mustParse(t, `
+schemas:
+  X<$Base>:
+    user: Omit($User, password_hash)
+  User<$Base>:
+    password_hash: string
+  Base:
+    id: int64
+`)
+	if got := fieldNames(doc.Schemas[0].Fields); strings.Join(got, ",") != "id,user" {
+		t.Errorf("got fields %v, want [id user]", got)
+	}
+
+	_, err := ParseDocument([]byte("schemas:\n  A<$B>:\n    a: string\n  B:\n    b: Omit($A, a)\n"))
+	if err == nil || !strings.Contains(err.Error(), "circular dependence") {
+		t.Errorf("got error %v, want circular dependence", err)
+	}
+}
+
+func TestOperatorsErrors(t *testing.T) {
+	for val, want := range map[string]string{
+		"Omit($User, email)":     "field `email` of `Omit` is not found",
+		"Pick($User)":            "fields should be specified",
+		"Partial($User, id)":     "doesn't accept fields",
+		"Omit(string, id)":       "should be custom type",
+		"Omit($User, id)[]":      "not allowed for arrays and maps",
+		"Omit($User, id)? = {}":  "default value are not allowed",
+		"Omit($Account, id)":     "`$Account` is not found",
+		"Pick($User, id, email)": "field `email` of `Pick` is not found",
+	} {
+		_, err := ParseDocument([]byte("schemas:\n  Public: " + val + "\n  User:\n    id: int64\n"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %q", val, err, want)
+		}
+	}
+}
+
+func fieldNames(fields []Schema) []string {
+	res := make([]string, 0, len(fields))
+	for _, f := range fields {
+		name := f.Name
+		if f.Optional {
+			name += "?"
+		}
+		res = append(res, name)
+	}
+	return res
+}
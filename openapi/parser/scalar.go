@@ -1,18 +1,35 @@
 package parser
 
 import (
+	"encoding/base64"
+	"errors"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type ScalarType struct {
-	Type        Type
-	Format      string
-	Optional    bool
-	IsArray     bool
-	Description string
-	Example     string
+	Type          Type
+	Format        string
+	Optional      bool
+	Nullable      bool
+	IsArray       bool
+	IsMap         bool
+	Enum          []string
+	Variants      []Type
+	Discriminator Discriminator
+	Constraints   Constraints
+	Default       string
+	Embeds        []Type
+	Operators     []Operator
+	Description   string
+	Example       string
 }
 
 func ParseScalarType(n *yaml.Node) (ScalarType, error) {
@@ -22,15 +39,51 @@ func ParseScalarType(n *yaml.Node) (ScalarType, error) {
 	res.Description = comment.Description
 	res.Example = comment.Example
 
-	val := n.Value
-	if strings.HasSuffix(val, "?") {
-		res.Optional = true
-		val = val[:len(val)-1]
+	var err error
+	val, def, hasDefault := splitDefault(n.Value)
+	val, res.Optional, res.Nullable = trimModifiers(val)
+	if lb := strings.Index(val, "{"); lb >= 0 {
+		rb := strings.LastIndex(val, "}")
+		if rb < lb {
+			return ScalarType{}, Err(n, "unclosed constraints block")
+		}
+		res.Constraints, err = ParseConstraints(val[lb+1 : rb])
+		if err != nil {
+			return ScalarType{}, Err(n, err.Error())
+		}
+		val = val[:lb] + val[rb+1:]
+		var optional, nullable bool
+		val, optional, nullable = trimModifiers(val)
+		res.Optional = res.Optional || optional
+		res.Nullable = res.Nullable || nullable
 	}
 	if strings.HasSuffix(val, "[]") {
 		res.IsArray = true
 		val = val[:len(val)-2]
 	}
+	if strings.HasPrefix(val, "map[") && strings.HasSuffix(val, "]") {
+		res.IsMap = true
+		val = val[4 : len(val)-1]
+		if val == "" {
+			return ScalarType{}, Err(n, "map value type should be specified, e.g. `map[string]`")
+		}
+	}
+	if IsOperator(val) {
+		if res.IsArray || res.IsMap {
+			return ScalarType{}, Err(n, "schema operators are not allowed for arrays and maps")
+		}
+		if !res.Constraints.IsEmpty() || hasDefault {
+			return ScalarType{}, Err(n, "constraints and default value are not allowed for schema operators")
+		}
+		embed, operators, err := ParseOperator(val)
+		if err != nil {
+			return ScalarType{}, Err(n, err.Error())
+		}
+		res.Type = TypeObject
+		res.Embeds = []Type{embed}
+		res.Operators = operators
+		return res, nil
+	}
 	if lp := strings.Index(val, "("); lp >= 0 {
 		rp := strings.LastIndex(val, ")")
 		if rp > lp {
@@ -38,20 +91,186 @@ func ParseScalarType(n *yaml.Node) (ScalarType, error) {
 			val = val[:lp]
 		}
 	}
-	if strings.HasPrefix(val, "$") {
+	var property string
+	if lb := strings.Index(val, "["); lb >= 0 && strings.HasSuffix(val, "]") {
+		if union := Type(val[:lb]); union.IsUnion() {
+			property = val[lb+1 : len(val)-1]
+			val = val[:lb]
+		}
+	}
+	if union := Type(val); union.IsUnion() {
+		res.Type = union
+		res.Variants, res.Discriminator, err = ParseUnion(res.Format, property)
+		if err != nil {
+			return ScalarType{}, Err(n, err.Error())
+		}
+		res.Format = ""
+	} else if strings.HasPrefix(val, "$") {
 		res.Type = Type(val)
-		return res, nil
+	} else {
+		res.Type, err = GetType(val)
+		if err != nil {
+			return ScalarType{}, Err(n, err.Error())
+		}
 	}
 
-	var err error
-	res.Type, err = GetType(val)
-	if err != nil {
+	if res.Type == TypeEnum {
+		res.Enum, err = ParseEnum(res.Format)
+		if err != nil {
+			return ScalarType{}, Err(n, err.Error())
+		}
+		res.Format = ""
+	}
+
+	// Default value of a custom type is checked by Validate when the type is known
+	if hasDefault {
+		if !res.Optional {
+			return ScalarType{}, Err(n, "default value is allowed for optional values only, e.g. `int32? = 20`")
+		}
+		if res.IsArray || res.IsMap {
+			return ScalarType{}, Err(n, "default value is not allowed for arrays and maps")
+		}
+		res.Default = unquote(def)
+		if res.Default == "" {
+			return ScalarType{}, Err(n, "default value should not be empty")
+		}
+		if _, err := ParseValue(res.Type, res.Enum, res.Default); err != nil && !res.Type.IsRef() {
+			return ScalarType{}, Err(n, "incorrect default value: "+err.Error())
+		}
+	}
+
+	if err := CheckConstraints(res.Constraints, res.Type, res.IsArray, res.Default); err != nil {
 		return ScalarType{}, Err(n, err.Error())
 	}
 
 	return res, nil
 }
 
+// splitDefault splits type and default value separated by `=` outside of brackets, e.g. `int32? = 20`
+func splitDefault(val string) (string, string, bool) {
+	depth := 0
+	for i, r := range val {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '=':
+			if depth == 0 {
+				return strings.TrimSpace(val[:i]), strings.TrimSpace(val[i+1:]), true
+			}
+		}
+	}
+	return val, "", false
+}
+
+// ParseValue parses value of the scalar type
+func ParseValue(t Type, enum []string, val string) (any, error) {
+	var res any = val
+	var err error
+	switch t {
+	case TypeBool:
+		res, err = strconv.ParseBool(val)
+	case TypeInt32:
+		res, err = strconv.ParseInt(val, 10, 32)
+	case TypeInt64:
+		res, err = strconv.ParseInt(val, 10, 64)
+	case TypeFloat:
+		// Value is checked as float32, but kept as float64 to avoid rounding artifacts
+		if _, err = strconv.ParseFloat(val, 32); err == nil {
+			res, err = strconv.ParseFloat(val, 64)
+		}
+	case TypeDouble:
+		res, err = strconv.ParseFloat(val, 64)
+	case TypeString:
+		return val, nil
+	case TypeUUID:
+		if !uuidRe.MatchString(val) {
+			return nil, errors.New("should be uuid")
+		}
+		return val, nil
+	case TypeDate:
+		_, err = time.Parse("2006-01-02", val)
+	case TypeDateTime:
+		_, err = time.Parse(time.RFC3339, val)
+	case TypeTime:
+		_, err = time.Parse("15:04:05", val)
+	case TypeDuration:
+		_, err = time.ParseDuration(val)
+	case TypeEmail:
+		_, err = mail.ParseAddress(val)
+	case TypeURI:
+		_, err = url.ParseRequestURI(val)
+	case TypeIPv4, TypeIPv6:
+		var addr netip.Addr
+		addr, err = netip.ParseAddr(val)
+		if err == nil && addr.Is4() != (t == TypeIPv4) {
+			err = errors.New("wrong ip version")
+		}
+	case TypeByte:
+		_, err = base64.StdEncoding.DecodeString(val)
+	case TypeDecimal:
+		if !decimalRe.MatchString(val) {
+			err = errors.New("not a decimal")
+		}
+	case TypeEnum:
+		for _, e := range enum {
+			if e == val {
+				return val, nil
+			}
+		}
+		return nil, errors.New("should be one of enum values")
+	default:
+		return nil, errors.New("values are not supported for type `" + string(t) + "`")
+	}
+	if err != nil {
+		return nil, errors.New("should be " + string(t))
+	}
+	return res, nil
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var decimalRe = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// trimModifiers trims optional (`?`) and nullable (`!null`) suffixes in any order
+func trimModifiers(val string) (string, bool, bool) {
+	var optional, nullable bool
+	for {
+		switch {
+		case strings.HasSuffix(val, "?"):
+			optional = true
+			val = val[:len(val)-1]
+		case strings.HasSuffix(val, "!null"):
+			nullable = true
+			val = val[:len(val)-5]
+		default:
+			return val, optional, nullable
+		}
+	}
+}
+
+// ParseEnum parses comma separated enum values, e.g. `active,blocked,deleted`
+func ParseEnum(val string) ([]string, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, errors.New("enum values should be specified, e.g. `enum(a,b,c)`")
+	}
+	values := []string{}
+	unique := map[string]struct{}{}
+	for _, v := range strings.Split(val, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return nil, errors.New("empty enum value")
+		}
+		if _, ok := unique[v]; ok {
+			return nil, errors.New("duplicate enum value `" + v + "`")
+		}
+		unique[v] = struct{}{}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
 type Comment struct {
 	Description string
 	Example     string
@@ -75,3 +294,26 @@ func ParseComment(comment string) Comment {
 
 	return res
 }
+
+// ParseNodeComment parses head and line comments of the key,
+// description is joined from both comments, example is taken from the line comment only
+func ParseNodeComment(n *yaml.Node) Comment {
+	res := ParseComment(n.LineComment)
+	if head := ParseHeadComment(n.HeadComment); head != "" {
+		res.Description = strings.TrimSpace(head + "\n" + res.Description)
+	}
+	return res
+}
+
+// ParseHeadComment joins lines of the multi-line comment
+func ParseHeadComment(comment string) string {
+	lines := []string{}
+	for _, l := range strings.Split(comment, "\n") {
+		l = strings.TrimSpace(l)
+		if !strings.HasPrefix(l, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(l[1:]))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
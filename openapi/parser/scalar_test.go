@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseScalarTypeDefault(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    string
+		wantErr string
+	}{
+		{val: "int32? = 20", want: "20"},
+		{val: "int32{min=1,max=100}? = 1", want: "1"},
+		{val: "string{min_length=2}? = 'ab'", want: "ab"},
+		{val: "enum(a,b)? = b", want: "b"},
+		{val: "$Status? = active", want: "active"},
+		{val: "int32 = 20", wantErr: "optional values only"},
+		{val: "int32[]? = 20", wantErr: "arrays and maps"},
+		{val: "int32? = a", wantErr: "should be int32"},
+		{val: "enum(a,b)? = c", wantErr: "enum values"},
+		{val: "int32{min=1}? = 0", wantErr: "greater than or equal to 1"},
+		{val: "int32{exclusive_max=10}? = 10", wantErr: "less than 10"},
+		{val: "string{max_length=2}? = abc", wantErr: "length should be at most 2"},
+		{val: "string{pattern=^[a-z]+$}? = A1", wantErr: "should match pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			res, err := ParseScalarType(&yaml.Node{Kind: yaml.ScalarNode, Value: tt.val})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Default != tt.want {
+				t.Errorf("got default %q, want %q", res.Default, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRefDefault(t *testing.T) {
+	const schemas = `
+schemas:
+  Status: enum(active,blocked)
+  User:
+    name: string
+`
+	if _, err := ParseDocument([]byte("api:\n  u:\n    'GET /u':\n      request:\n        query:\n          status: $Status? = active\n" + schemas)); err != nil {
+		t.Errorf("enum reference default: %v", err)
+	}
+
+	for field, want := range map[string]string{
+		"status: $Status? = deleted": "should be one of enum values",
+		"user: $User? = x":           "are enums",
+	} {
+		_, diags := Diagnose("", []byte("api:\n  u:\n    'GET /u':\n      request:\n        query:\n          "+field+"\n"+schemas))
+		if !diags.HasErrors() || !strings.Contains(diags[0].Message, want) {
+			t.Errorf("%s: got %v, want error %q", field, diags, want)
+		}
+	}
+}
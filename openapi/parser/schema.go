@@ -1,31 +1,51 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-func ParseSchemas(n *yaml.Node) ([]Schema, error) {
+// ParseSchemas parses schemas section, generic schemas are returned separately
+// and are parsed on instantiation. Schemas with errors are returned as empty objects,
+// so references to them are not reported as unknown types
+func ParseSchemas(n *yaml.Node) ([]Schema, []Generic, error) {
 	schemas := []Schema{}
+	generics := []Generic{}
 
 	pairs, err := PairNodes(n)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// Schemas are parsed independently to report all errors
+	var errs Diagnostics
 	for _, p := range pairs {
+		g, ok, err := ParseGeneric(&p)
+		if err != nil {
+			errs.add(err)
+			continue
+		}
+		if ok {
+			generics = append(generics, g)
+			continue
+		}
 		s, err := ParseSchema(&p)
 		if err != nil {
-			return nil, err
+			errs.add(err)
+			name, _ := ParseSchemaDefinition(p.Left.Value)
+			s = Schema{Name: name, Type: TypeObject, Pos: Pos{Line: p.Left.Line, Column: p.Left.Column}}
 		}
 		schemas = append(schemas, s)
 	}
 
-	return schemas, nil
+	return schemas, generics, errs.Err()
 }
 
 func ParseSchema(p *NodePair) (Schema, error) {
-	schema := Schema{}
+	schema := Schema{Pos: Pos{Line: p.Left.Line, Column: p.Left.Column}}
 
 	if p.Left.Kind != yaml.ScalarNode {
 		return Schema{}, Err(p.Left, "non scalar value")
@@ -36,6 +56,10 @@ func ParseSchema(p *NodePair) (Schema, error) {
 	}
 	schema.Name, schema.Embeds = ParseSchemaDefinition(p.Left.Value)
 
+	if isLongForm(p.Right) {
+		return parseLongForm(p)
+	}
+
 	comment := ParseComment(p.Left.LineComment)
 	schema.Description = comment.Description
 	schema.Example = comment.Example
@@ -58,7 +82,16 @@ func ParseSchema(p *NodePair) (Schema, error) {
 		schema.Type = scalar.Type
 		schema.Format = scalar.Format
 		schema.Optional = scalar.Optional
+		schema.Nullable = scalar.Nullable
 		schema.IsArray = scalar.IsArray
+		schema.IsMap = scalar.IsMap
+		schema.Enum = scalar.Enum
+		schema.Variants = scalar.Variants
+		schema.Discriminator = scalar.Discriminator
+		schema.Constraints = scalar.Constraints
+		schema.Default = scalar.Default
+		schema.Embeds = append(schema.Embeds, scalar.Embeds...)
+		schema.Operators = scalar.Operators
 
 	case yaml.MappingNode:
 		schema.Type = TypeObject
@@ -82,9 +115,110 @@ func ParseSchema(p *NodePair) (Schema, error) {
 		return Schema{}, Err(p.Right, "unknown type")
 	}
 
+	if head := ParseHeadComment(p.Left.HeadComment); head != "" {
+		schema.Description = strings.TrimSpace(head + "\n" + schema.Description)
+	}
+
+	// Examples from comments are checked by Validate, incorrect ones are reported as warnings
 	return schema, nil
 }
 
+// LongFormType is the key of the long form declaration, other keys are optional,
+// e.g. `{_type: string, description: Name, example: John}`
+const LongFormType = "_type"
+
+var longFormKeys = map[string]struct{}{LongFormType: {}, "description": {}, "example": {}, "deprecated": {}}
+
+// isLongForm checks that the value is declared in the long form with the `_type` key,
+// an object with fields `type`, `description`, etc. is a regular object
+func isLongForm(n *yaml.Node) bool {
+	if n.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == LongFormType {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLongForm(p *NodePair) (Schema, error) {
+	pairs, err := PairNodes(p.Right)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	meta := map[string]*yaml.Node{}
+	for _, f := range pairs {
+		if _, ok := longFormKeys[f.Left.Value]; !ok {
+			return Schema{}, Err(f.Left, "unknown key of the long form declaration, allowed: `_type`, `description`, `example`, `deprecated`")
+		}
+		meta[f.Left.Value] = f.Right
+	}
+
+	schema, err := ParseSchema(&NodePair{Left: p.Left, Right: meta[LongFormType]})
+	if err != nil {
+		return Schema{}, err
+	}
+
+	if n, ok := meta["description"]; ok {
+		schema.Description = strings.TrimSpace(n.Value)
+	}
+	if n, ok := meta["deprecated"]; ok {
+		schema.Deprecated, err = strconv.ParseBool(strings.TrimSpace(n.Value))
+		if err != nil {
+			return Schema{}, Err(n, "deprecated should be bool")
+		}
+	}
+	if n, ok := meta["example"]; ok {
+		schema.Example, err = nodeExample(n)
+		if err != nil {
+			return Schema{}, Err(n, err.Error())
+		}
+		if _, err := ParseExample(schema); err != nil {
+			return Schema{}, Err(n, "incorrect example: "+err.Error())
+		}
+	}
+
+	return schema, nil
+}
+
+// nodeExample returns example text, objects and arrays are converted to JSON
+func nodeExample(n *yaml.Node) (string, error) {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value, nil
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseExample parses example according to the type, objects, arrays and maps are expected in JSON,
+// examples of such types which are not valid JSON are kept as strings
+func ParseExample(s Schema) (any, error) {
+	if s.Example == "" {
+		return nil, nil
+	}
+
+	isScalar := !s.IsArray && !s.IsMap && !s.Type.IsRef() && !s.Type.IsUnion()
+	if isScalar && s.Type != TypeObject && s.Type != TypeAny && s.Type != TypeFile {
+		return ParseValue(s.Type, s.Enum, s.Example)
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(s.Example), &v); err != nil {
+		return s.Example, nil
+	}
+	return v, nil
+}
+
 func ParseSchemaDefinition(val string) (string, []Type) {
 	embStart := strings.Index(val, "<")
 	embEnd := strings.LastIndex(val, ">")
@@ -96,28 +230,86 @@ func ParseSchemaDefinition(val string) (string, []Type) {
 	val = val[embStart+1 : embEnd]
 	val = strings.ReplaceAll(val, " ", "")
 	embs := []Type{}
-	for _, e := range strings.Split(val, ",") {
+	for _, e := range splitList(val) {
 		embs = append(embs, Type(e))
 	}
 
 	return typ, embs
 }
 
-func resolveEmbeds(s *Schema, names map[Type]*Schema, resolved *map[Type]struct{}) {
+// resolveSchema resolves embeds of the named schema once, embedded schemas are resolved first
+func resolveSchema(name Type, names map[Type]*Schema, resolved *map[Type]struct{}) error {
+	if _, ok := (*resolved)[name]; ok {
+		return nil
+	}
+	if err := resolveEmbeds(names[name], names, resolved); err != nil {
+		return err
+	}
+	(*resolved)[name] = struct{}{}
+	return nil
+}
+
+// resolveEmbeds merges fields of embedded schemas and applies operators, embeds and operators are cleared,
+// so resolving the schema again (e.g. shared common response) doesn't restore fields removed by operators
+func resolveEmbeds(s *Schema, names map[Type]*Schema, resolved *map[Type]struct{}) error {
 	for _, e := range s.Embeds {
-		if _, ok := (*resolved)[e]; !ok {
-			resolveEmbeds(names[e], names, resolved)
+		if _, ok := names[e]; !ok {
+			return atPos(ErrCode(nil, CodeUnknownEmbed, "embedded schema `"+string(e)+"` is not found"), s.Pos)
+		}
+		if err := resolveSchema(e, names, resolved); err != nil {
+			return err
 		}
 	}
 	for i := len(s.Embeds) - 1; i >= 0; i-- {
 		s.Fields = MergeFields(s.Fields, names[s.Embeds[i]].Fields)
 	}
+	s.Embeds = nil
+
+	var err error
+	if s.Fields, err = ApplyOperators(s.Fields, s.Operators); err != nil {
+		return atPos(Err(nil, fmt.Sprintf("%s in `%s`", err.Error(), s.Name)), s.Pos)
+	}
+	s.Operators = nil
+
+	// Fields added to the reference (merged common body) don't override fields of the referenced schema
+	if _, ok := names[s.Type]; ok && len(s.Fields) != 0 {
+		if err := resolveSchema(s.Type, names, resolved); err != nil {
+			return err
+		}
+		s.Fields = ExtraFields(s.Fields, names[s.Type].Fields)
+	}
+
+	// Siblings of $ref are not supported by generators, so enum reference with default value is inlined
+	if ref, ok := names[s.Type]; ok && s.Default != "" && ref.Type == TypeEnum && !ref.IsArray && !ref.IsMap {
+		s.Type = ref.Type
+		s.Enum = ref.Enum
+		if s.Description == "" {
+			s.Description = ref.Description
+		}
+	}
 
 	for i := 0; i < len(s.Fields); i++ {
-		resolveEmbeds(&s.Fields[i], names, resolved)
+		if err := resolveEmbeds(&s.Fields[i], names, resolved); err != nil {
+			return err
+		}
 	}
 
-	(*resolved)[Type("$"+s.Name)] = struct{}{}
+	return nil
+}
+
+// ExtraFields returns fields which are not declared in the base fields
+func ExtraFields(fields, base []Schema) []Schema {
+	declared := map[string]struct{}{}
+	for _, f := range base {
+		declared[f.Name] = struct{}{}
+	}
+	res := []Schema{}
+	for _, f := range fields {
+		if _, ok := declared[f.Name]; !ok {
+			res = append(res, f)
+		}
+	}
+	return res
 }
 
 func MergeFields(prior, minor []Schema) []Schema {
@@ -147,7 +339,9 @@ func checkCircularDependence(name Type, names map[Type]*Schema, check *map[Type]
 	if _, ok := (*check)[name]; ok {
 		return Err(nil, "circular dependence for `"+string(name)+"` found")
 	}
+	// Schemas are tracked only along the current path, so shared embeds of sibling branches are not a cycle
 	(*check)[name] = struct{}{}
+	defer delete(*check, name)
 	embeds := findAllEmbeds(names[name])
 	for _, e := range embeds {
 		if err := checkCircularDependence(e, names, check); err != nil {
@@ -164,12 +358,12 @@ func findAllEmbeds(s *Schema) []Type {
 		res = append(res, findAllEmbeds(&f)...)
 	}
 	m := map[Type]struct{}{}
-	for _, r := range res {
-		m[r] = struct{}{}
-	}
 	unique := []Type{}
-	for k := range m {
-		unique = append(unique, k)
+	for _, r := range res {
+		if _, ok := m[r]; !ok {
+			m[r] = struct{}{}
+			unique = append(unique, r)
+		}
 	}
 	return unique
 }
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLongForm(t *testing.T) {
+	doc := mustParse(t, `
+schemas:
+  User:
+    # Name of the user,
+    # may be empty
+    name:
+      _type: string?
+      description: Full name
+      example: John
+      deprecated: true
+    tags:
+      _type: string[]
+      example: [a, b]
+  Event:
+    type: string
+    description: string
+`)
+	name := doc.Schemas[0].Fields[0]
+	if name.Type != TypeString || !name.Optional || name.Description != "Full name" || name.Example != "John" || !name.Deprecated {
+		t.Errorf("got long form %+v", name)
+	}
+	if tags := doc.Schemas[0].Fields[1]; !tags.IsArray || tags.Example != `["a","b"]` {
+		t.Errorf("got example %s, want JSON array", tags.Example)
+	}
+
+	// Object with `type` and `description` fields is a regular object
+	event := doc.Schemas[1]
+	if event.Type != TypeObject || strings.Join(fieldNames(event.Fields), ",") != "type,description" {
+		t.Errorf("got %s with fields %v, want object with type and description", event.Type, fieldNames(event.Fields))
+	}
+}
+
+func TestParseLongFormErrors(t *testing.T) {
+	for value, want := range map[string]string{
+		"{_type: int64, example: abc}":   "incorrect example: should be int64",
+		"{_type: int64, name: string}":   "unknown key of the long form declaration",
+		"{_type: int64, deprecated: 1a}": "deprecated should be bool",
+		"{_type: foo}":                   "unknown scalar type",
+	} {
+		_, err := ParseDocument([]byte("schemas:\n  User:\n    id: " + value + "\n"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %q", value, err, want)
+		}
+	}
+}
+
+func TestCommentExamples(t *testing.T) {
+	doc, diags := Diagnose("", []byte(`
+schemas:
+  User:
+    id: int64 # ID (42)
+    name: string # Name (John)
+    age: int32 # Age (unknown)
+    tags: string[] # (["a"])
+`))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	// Comment examples which don't match the type are warnings to keep existing files valid
+	if len(diags) != 1 || diags[0].Severity != SeverityWarning || diags[0].Code != CodeExample || diags[0].Line != 6 {
+		t.Errorf("got diagnostics %v, want a single example warning at line 6", diags)
+	}
+
+	tests := map[string]any{"id": int64(42), "name": "John", "tags": []any{"a"}}
+	for _, f := range doc.Schemas[0].Fields {
+		want, ok := tests[f.Name]
+		if !ok {
+			continue
+		}
+		got, err := ParseExample(f)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got example %#v (%v), want %#v", f.Name, got, err, want)
+		}
+	}
+}
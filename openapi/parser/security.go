@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecurityRequirement maps names of security schemes to required scopes,
+// an empty requirement means that authorization is optional
+type SecurityRequirement map[string][]string
+
+type SecurityType string
+
+const (
+	SecurityTypeHTTP   SecurityType = "http"
+	SecurityTypeAPIKey SecurityType = "apikey"
+	SecurityTypeOAuth2 SecurityType = "oauth2"
+)
+
+type SecurityScheme struct {
+	Name         string
+	Type         SecurityType
+	Scheme       string
+	BearerFormat string
+	In           string
+	Param        string
+	Description  string
+	Flows        []OAuthFlow
+}
+
+type OAuthFlowType string
+
+const (
+	OAuthFlowImplicit          OAuthFlowType = "implicit"
+	OAuthFlowPassword          OAuthFlowType = "password"
+	OAuthFlowClientCredentials OAuthFlowType = "client_credentials"
+	OAuthFlowAuthorizationCode OAuthFlowType = "authorization_code"
+)
+
+type OAuthFlow struct {
+	Type             OAuthFlowType
+	AuthorizationURL string
+	TokenURL         string
+	RefreshURL       string
+	Scopes           []OAuthScope
+}
+
+type OAuthScope struct {
+	Name        string
+	Description string
+}
+
+// DefaultSecurityScheme is used if no security schemes are declared
+var DefaultSecurityScheme = SecurityScheme{
+	Name:   "bearerAuth",
+	Type:   SecurityTypeHTTP,
+	Scheme: "bearer",
+}
+
+// ParseSecurity parses list of security requirements, e.g. `[{}, {bearerAuth: []}, {oauth: [users:read]}]`,
+// `none` or an empty list means that no authorization is required
+func ParseSecurity(n *yaml.Node) ([]SecurityRequirement, error) {
+	res := []SecurityRequirement{}
+
+	if n.Kind == yaml.ScalarNode && strings.TrimSpace(n.Value) == "none" {
+		return res, nil
+	}
+	if n.Kind != yaml.SequenceNode {
+		return nil, Err(n, "security should be list of requirements or `none`")
+	}
+
+	for _, item := range n.Content {
+		req := SecurityRequirement{}
+		switch item.Kind {
+		case yaml.ScalarNode:
+			req[strings.TrimSpace(item.Value)] = []string{}
+		case yaml.MappingNode:
+			pairs, err := PairNodes(item)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pairs {
+				scopes, err := parseScopes(p.Right)
+				if err != nil {
+					return nil, err
+				}
+				req[p.Left.Value] = scopes
+			}
+		default:
+			return nil, Err(item, "incorrect security requirement format")
+		}
+		res = append(res, req)
+	}
+
+	return res, nil
+}
+
+func parseScopes(n *yaml.Node) ([]string, error) {
+	scopes := []string{}
+	switch {
+	case isNull(n):
+	case n.Kind == yaml.SequenceNode:
+		for _, s := range n.Content {
+			if s.Kind != yaml.ScalarNode {
+				return nil, Err(s, "scope should be a string")
+			}
+			scopes = append(scopes, s.Value)
+		}
+	default:
+		return nil, Err(n, "scopes should be a list")
+	}
+	return scopes, nil
+}
+
+func ParseSecuritySchemes(n *yaml.Node) ([]SecurityScheme, error) {
+	schemes := []SecurityScheme{}
+
+	pairs, err := PairNodes(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		s, err := ParseSecurityScheme(&p)
+		if err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, s)
+	}
+
+	return schemes, nil
+}
+
+// ParseSecurityScheme parses scheme declared via shorthand
+// (`bearer`, `bearer(JWT)`, `basic`, `header(X-API-Key)`, `query(key)`, `cookie(session)`)
+// or via full form with `type`, `scheme`, `bearer_format`, `in`, `name` and `flows` fields
+func ParseSecurityScheme(p *NodePair) (SecurityScheme, error) {
+	scheme := SecurityScheme{
+		Name:        p.Left.Value,
+		Description: ParseComment(p.Left.LineComment).Description,
+	}
+
+	switch p.Right.Kind {
+	case yaml.ScalarNode:
+		if comment := ParseComment(p.Right.LineComment); comment.Description != "" {
+			scheme.Description = comment.Description
+		}
+		val := strings.TrimSpace(p.Right.Value)
+		var arg string
+		if lp := strings.Index(val, "("); lp >= 0 && strings.HasSuffix(val, ")") {
+			arg = strings.TrimSpace(val[lp+1 : len(val)-1])
+			val = val[:lp]
+		}
+		switch val {
+		case "bearer", "basic":
+			scheme.Type = SecurityTypeHTTP
+			scheme.Scheme = val
+			scheme.BearerFormat = arg
+		case "header", "query", "cookie":
+			scheme.Type = SecurityTypeAPIKey
+			scheme.In = val
+			scheme.Param = arg
+		default:
+			return SecurityScheme{}, Err(p.Right, "unknown security scheme `"+val+"`")
+		}
+
+	case yaml.MappingNode:
+		pairs, err := PairNodes(p.Right)
+		if err != nil {
+			return SecurityScheme{}, err
+		}
+		for _, f := range pairs {
+			val := strings.TrimSpace(f.Right.Value)
+			switch f.Left.Value {
+			case "type":
+				scheme.Type = SecurityType(strings.ToLower(val))
+			case "scheme":
+				scheme.Scheme = val
+			case "bearer_format":
+				scheme.BearerFormat = val
+			case "in":
+				scheme.In = val
+			case "name":
+				scheme.Param = val
+			case "description":
+				scheme.Description = val
+			case "flows":
+				scheme.Flows, err = ParseOAuthFlows(f.Right)
+				if err != nil {
+					return SecurityScheme{}, err
+				}
+			default:
+				return SecurityScheme{}, Err(f.Left, "unknown field of a security scheme")
+			}
+		}
+
+	default:
+		return SecurityScheme{}, Err(p.Right, "incorrect security scheme format")
+	}
+
+	if err := checkSecurityScheme(&scheme); err != nil {
+		return SecurityScheme{}, Err(p.Right, err.Error())
+	}
+
+	return scheme, nil
+}
+
+func checkSecurityScheme(s *SecurityScheme) error {
+	switch s.Type {
+	case SecurityTypeHTTP:
+		if s.Scheme == "" {
+			return fmt.Errorf("http security scheme `%s` should have scheme", s.Name)
+		}
+		if s.BearerFormat != "" && s.Scheme != "bearer" {
+			return fmt.Errorf("bearer format is allowed for bearer scheme only")
+		}
+	case SecurityTypeAPIKey:
+		if s.In != "header" && s.In != "query" && s.In != "cookie" {
+			return fmt.Errorf("api key `%s` should be in header, query or cookie", s.Name)
+		}
+		if s.Param == "" {
+			return fmt.Errorf("api key `%s` should have name, e.g. `%s(X-API-Key)`", s.Name, s.In)
+		}
+	case SecurityTypeOAuth2:
+		if len(s.Flows) == 0 {
+			return fmt.Errorf("oauth2 security scheme `%s` should have flows", s.Name)
+		}
+	default:
+		return fmt.Errorf("unknown security scheme type `%s` (http/apikey/oauth2 only)", s.Type)
+	}
+	return nil
+}
+
+func ParseOAuthFlows(n *yaml.Node) ([]OAuthFlow, error) {
+	flows := []OAuthFlow{}
+
+	pairs, err := PairNodes(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		flow := OAuthFlow{Type: OAuthFlowType(p.Left.Value)}
+		switch flow.Type {
+		case OAuthFlowImplicit, OAuthFlowPassword, OAuthFlowClientCredentials, OAuthFlowAuthorizationCode:
+		default:
+			return nil, Err(p.Left, "unknown oauth2 flow (implicit/password/client_credentials/authorization_code only)")
+		}
+
+		fields, err := PairNodes(p.Right)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			switch f.Left.Value {
+			case "authorization_url":
+				flow.AuthorizationURL = strings.TrimSpace(f.Right.Value)
+			case "token_url":
+				flow.TokenURL = strings.TrimSpace(f.Right.Value)
+			case "refresh_url":
+				flow.RefreshURL = strings.TrimSpace(f.Right.Value)
+			case "scopes":
+				if isNull(f.Right) {
+					continue
+				}
+				scopes, err := PairNodes(f.Right)
+				if err != nil {
+					return nil, err
+				}
+				for _, s := range scopes {
+					flow.Scopes = append(flow.Scopes, OAuthScope{
+						Name:        s.Left.Value,
+						Description: strings.TrimSpace(s.Right.Value),
+					})
+				}
+			default:
+				return nil, Err(f.Left, "unknown field of an oauth2 flow")
+			}
+		}
+
+		needAuthURL := flow.Type == OAuthFlowImplicit || flow.Type == OAuthFlowAuthorizationCode
+		if needAuthURL && flow.AuthorizationURL == "" {
+			return nil, Err(p.Left, "oauth2 flow should have authorization_url")
+		}
+		if flow.Type != OAuthFlowImplicit && flow.TokenURL == "" {
+			return nil, Err(p.Left, "oauth2 flow should have token_url")
+		}
+
+		flows = append(flows, flow)
+	}
+
+	return flows, nil
+}
+
+// CheckSecurity checks that requirements refer to declared schemes and scopes
+func CheckSecurity(reqs []SecurityRequirement, schemes []SecurityScheme) error {
+	names := map[string]*SecurityScheme{}
+	for i := range schemes {
+		names[schemes[i].Name] = &schemes[i]
+	}
+
+	for _, req := range reqs {
+		for _, name := range req.Names() {
+			scopes := req[name]
+			s, ok := names[name]
+			if !ok {
+				return fmt.Errorf("security scheme `%s` is not found", name)
+			}
+			if len(scopes) == 0 {
+				continue
+			}
+			if s.Type != SecurityTypeOAuth2 {
+				return fmt.Errorf("scopes are allowed for oauth2 security schemes only (`%s`)", name)
+			}
+			for _, scope := range scopes {
+				if !s.HasScope(scope) {
+					return fmt.Errorf("scope `%s` is not declared in `%s`", scope, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Names returns sorted names of required schemes
+func (r SecurityRequirement) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *SecurityScheme) HasScope(scope string) bool {
+	for _, f := range s.Flows {
+		for _, sc := range f.Scopes {
+			if sc.Name == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -27,9 +27,42 @@ func ParseSettings(n *yaml.Node) (Settings, error) {
 		case "title":
 			settings.Title = strings.TrimSpace(p.Right.Value)
 		case "security":
-			// TODO
+			settings.Security, err = ParseSecurity(p.Right)
+			if err != nil {
+				return Settings{}, err
+			}
+		case "security_schemes":
+			settings.SecuritySchemes, err = ParseSecuritySchemes(p.Right)
+			if err != nil {
+				return Settings{}, err
+			}
 		}
 	}
 
+	fillSecurityDefaults(&settings)
+
+	if err := CheckSecurity(settings.Security, settings.SecuritySchemes); err != nil {
+		return Settings{}, Err(n, err.Error())
+	}
+
 	return settings, nil
 }
+
+func fillSecurityDefaults(settings *Settings) {
+	if settings.SecuritySchemes != nil {
+		return
+	}
+
+	// Optional bearer authorization is used by default,
+	// `bearer` is kept as an alias of the default scheme
+	settings.SecuritySchemes = []SecurityScheme{DefaultSecurityScheme}
+	if settings.Security == nil {
+		settings.Security = []SecurityRequirement{{}, {DefaultSecurityScheme.Name: {}}}
+	}
+	for _, req := range settings.Security {
+		if scopes, ok := req["bearer"]; ok {
+			delete(req, "bearer")
+			req[DefaultSecurityScheme.Name] = scopes
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Discriminator struct {
+	Property string
+	Mapping  []DiscriminatorMapping
+}
+
+type DiscriminatorMapping struct {
+	Value string
+	Type  Type
+}
+
+// ParseUnion parses variants of oneOf/anyOf type, e.g. `$A,$B` or `a=$A,b=$B`,
+// values of the mapping are allowed only with discriminator property
+func ParseUnion(val string, property string) ([]Type, Discriminator, error) {
+	disc := Discriminator{Property: strings.TrimSpace(property)}
+	if strings.TrimSpace(val) == "" {
+		return nil, Discriminator{}, errors.New("union variants should be specified, e.g. `oneOf($A,$B)`")
+	}
+
+	variants := []Type{}
+	unique := map[Type]struct{}{}
+	values := map[string]struct{}{}
+	for _, v := range splitList(val) {
+		value, typ, hasValue := strings.Cut(v, "=")
+		if !hasValue {
+			typ = value
+		}
+		value = strings.TrimSpace(value)
+		t := Type(strings.TrimSpace(typ))
+		if t == "" {
+			return nil, Discriminator{}, errors.New("empty union variant")
+		}
+
+		if !t.IsRef() {
+			var err error
+			if t, err = GetType(string(t)); err != nil || t == TypeEnum {
+				return nil, Discriminator{}, fmt.Errorf("unknown union variant type `%s`", typ)
+			}
+			if disc.Property != "" {
+				return nil, Discriminator{}, errors.New("union with discriminator should contain custom types only")
+			}
+		}
+		if _, ok := unique[t]; ok {
+			return nil, Discriminator{}, fmt.Errorf("duplicate union variant `%s`", t)
+		}
+		unique[t] = struct{}{}
+		variants = append(variants, t)
+
+		if hasValue {
+			if disc.Property == "" {
+				return nil, Discriminator{}, errors.New("union mapping requires discriminator, e.g. `oneOf[type](a=$A,b=$B)`")
+			}
+			if _, ok := values[value]; ok || value == "" {
+				return nil, Discriminator{}, fmt.Errorf("incorrect discriminator value `%s`", value)
+			}
+			values[value] = struct{}{}
+			disc.Mapping = append(disc.Mapping, DiscriminatorMapping{Value: value, Type: t})
+		}
+	}
+
+	return variants, disc, nil
+}
+
+// CheckUnions checks that variants with discriminator are objects containing discriminator property,
+// embeds should be resolved
+func CheckUnions(doc *Document) Diagnostics {
+	names := map[string]*Schema{}
+	for i := range doc.Schemas {
+		names[doc.Schemas[i].Name] = &doc.Schemas[i]
+	}
+
+	var diags Diagnostics
+	var check func(s *Schema, pos Pos)
+	check = func(s *Schema, pos Pos) {
+		if s.Pos.Line != 0 {
+			pos = s.Pos
+		}
+		for i := range s.Fields {
+			check(&s.Fields[i], pos)
+		}
+		if s.Discriminator.Property == "" {
+			return
+		}
+		for _, v := range s.Variants {
+			ref, ok := names[v.Name()]
+			if !ok {
+				continue
+			}
+			if ref.Type != TypeObject || ref.IsArray || ref.IsMap || !hasField(ref, s.Discriminator.Property) {
+				diags = append(diags, Diagnostic{
+					File:     pos.File,
+					Line:     pos.Line,
+					Column:   pos.Column,
+					Severity: SeverityError,
+					Code:     CodeUnion,
+					Message: fmt.Sprintf(
+						"union variant `%s` should be object with discriminator field `%s`",
+						v, s.Discriminator.Property,
+					),
+				})
+			}
+		}
+	}
+
+	for i := range doc.Schemas {
+		check(&doc.Schemas[i], Pos{})
+	}
+	for i := range doc.API.Methods {
+		m := &doc.API.Methods[i]
+		for _, s := range methodSchemas(m) {
+			check(s, m.Pos)
+		}
+	}
+
+	return diags
+}
+
+func hasField(s *Schema, name string) bool {
+	for _, f := range s.Fields {
+		if f.Name == name && !f.Optional {
+			return true
+		}
+	}
+	return false
+}
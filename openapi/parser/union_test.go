@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnion(t *testing.T) {
+	variants, disc, err := ParseUnion("created=$UserCreated, deleted=$UserDeleted", "type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 2 || variants[0] != "$UserCreated" || variants[1] != "$UserDeleted" {
+		t.Errorf("got variants %v", variants)
+	}
+	if disc.Property != "type" || len(disc.Mapping) != 2 || disc.Mapping[1] != (DiscriminatorMapping{Value: "deleted", Type: "$UserDeleted"}) {
+		t.Errorf("got discriminator %+v", disc)
+	}
+
+	variants, disc, err = ParseUnion("string,float64,$A", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 3 || variants[1] != TypeDouble || disc.Property != "" {
+		t.Errorf("got variants %v, discriminator %+v", variants, disc)
+	}
+}
+
+func TestParseUnionErrors(t *testing.T) {
+	tests := []struct {
+		val, property, want string
+	}{
+		{val: "", want: "union variants should be specified"},
+		{val: "$A,", want: "empty union variant"},
+		{val: "foo", want: "unknown union variant type `foo`"},
+		{val: "enum", want: "unknown union variant type `enum`"},
+		{val: "$A,string", property: "type", want: "custom types only"},
+		{val: "$A,$A", want: "duplicate union variant `$A`"},
+		{val: "a=$A", want: "union mapping requires discriminator"},
+		{val: "a=$A,a=$B", property: "type", want: "incorrect discriminator value `a`"},
+	}
+	for _, tt := range tests {
+		if _, _, err := ParseUnion(tt.val, tt.property); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s[%s]: got error %v, want %q", tt.val, tt.property, err, tt.want)
+		}
+	}
+}
+
+func TestCheckUnions(t *testing.T) {
+	_, diags := Diagnose("", []byte(`
+schemas:
+  Event: oneOf[type](created=$Created,$Deleted,$Plain)
+  Base:
+    type: string
+  Created<$Base>:
+    id: int64
+  Deleted:
+    id: int64
+  Plain: string
+  Value: anyOf(string,$Deleted)
+`))
+	want := []string{
+		"union variant `$Deleted` should be object with discriminator field `type`",
+		"union variant `$Plain` should be object with discriminator field `type`",
+	}
+	got := []string{}
+	for _, d := range diags {
+		if d.Code != CodeUnion || d.Line != 3 {
+			t.Errorf("got diagnostic %+v, want union error at line 3", d)
+		}
+		got = append(got, d.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -32,11 +32,12 @@ func PairNodes(n *yaml.Node) ([]NodePair, error) {
 	return pairs, nil
 }
 
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
 func Err(n *yaml.Node, msg string) error {
-	if n == nil {
-		return fmt.Errorf("%v", msg)
-	}
-	return fmt.Errorf("%v (Line: %v, Column: %v)", msg, n.Line, n.Column)
+	return ErrCode(n, CodeInvalid, msg)
 }
 
 func PrintPair(pair *NodePair) {
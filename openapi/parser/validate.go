@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// Validate checks references of schemas and methods before embeds are resolved and returns all found problems:
+// unknown types, embeds and union variants, circular embeds, security requirements and incorrect examples of comments
+func Validate(doc *Document) Diagnostics {
+	v := &validator{names: map[string]*Schema{}}
+	for i := range doc.Schemas {
+		v.names[doc.Schemas[i].Name] = &doc.Schemas[i]
+	}
+
+	for i := range doc.Schemas {
+		v.schema(&doc.Schemas[i], doc.Schemas[i].Pos)
+	}
+	for i := range doc.API.Methods {
+		m := &doc.API.Methods[i]
+		if err := CheckSecurity(m.Security, doc.Settings.SecuritySchemes); err != nil {
+			v.add(m.Pos, SeverityError, CodeSecurity, fmt.Sprintf("%s (method `%s %s`)", err.Error(), m.Method, m.Path))
+		}
+		for _, s := range methodSchemas(m) {
+			v.schema(s, m.Pos)
+		}
+	}
+
+	// Cycles are checked only when all embeds exist, each cycle is reported once
+	if !v.unknownEmbeds {
+		names := map[Type]*Schema{}
+		for i := range doc.Schemas {
+			names[Type("$"+doc.Schemas[i].Name)] = &doc.Schemas[i]
+		}
+		reported := map[string]struct{}{}
+		for _, s := range doc.Schemas {
+			err := checkCircularDependence(Type("$"+s.Name), names, &map[Type]struct{}{})
+			if err == nil {
+				continue
+			}
+			for _, d := range ToDiagnostics(err) {
+				if _, ok := reported[d.Message]; ok {
+					continue
+				}
+				reported[d.Message] = struct{}{}
+				v.add(s.Pos, SeverityError, CodeCircular, d.Message)
+			}
+		}
+	}
+
+	return v.diags
+}
+
+type validator struct {
+	names         map[string]*Schema
+	diags         Diagnostics
+	unknownEmbeds bool
+}
+
+func (v *validator) add(pos Pos, severity Severity, code string, msg string) {
+	v.diags = append(v.diags, Diagnostic{
+		File:     pos.File,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: severity,
+		Code:     code,
+		Message:  msg,
+	})
+}
+
+// schema checks the schema and its fields, parent position is used for schemas without it
+func (v *validator) schema(s *Schema, parent Pos) {
+	pos := s.Pos
+	if pos.Line == 0 {
+		pos = parent
+	}
+
+	if s.Type.IsRef() && v.names[s.Type.Name()] == nil {
+		v.add(pos, SeverityError, CodeUnknownType, "type `"+string(s.Type)+"` is not found")
+	} else if s.Type.IsRef() && s.Default != "" {
+		v.refDefault(s, pos)
+	}
+
+	if _, err := ParseExample(*s); err != nil {
+		v.add(pos, SeverityWarning, CodeExample, "incorrect example is skipped: "+err.Error())
+	}
+
+	for _, e := range s.Embeds {
+		ref, ok := v.names[e.Name()]
+		switch {
+		case !e.IsRef() || !ok:
+			v.unknownEmbeds = true
+			v.add(pos, SeverityError, CodeUnknownEmbed, "embedded schema `"+string(e)+"` is not found")
+		case ref.Type != TypeObject || ref.IsArray || ref.IsMap:
+			v.add(pos, SeverityWarning, CodeEmbed, "embedded schema `"+string(e)+"` is not an object, it has no fields")
+		}
+	}
+
+	for _, t := range s.Variants {
+		if t.IsRef() && v.names[t.Name()] == nil {
+			v.add(pos, SeverityError, CodeUnknownType, "union variant `"+string(t)+"` is not found")
+		}
+	}
+
+	for i := range s.Fields {
+		v.schema(&s.Fields[i], pos)
+	}
+}
+
+// refDefault checks default value of the reference, it is allowed for enum schemas only
+func (v *validator) refDefault(s *Schema, pos Pos) {
+	ref := v.names[s.Type.Name()]
+	if ref.Type != TypeEnum || ref.IsArray || ref.IsMap {
+		v.add(pos, SeverityError, CodeInvalid, "default value of `"+string(s.Type)+"` is not allowed, custom types can have default values if they are enums")
+		return
+	}
+	if _, err := ParseValue(ref.Type, ref.Enum, s.Default); err != nil {
+		v.add(pos, SeverityError, CodeInvalid, "incorrect default value: "+err.Error())
+	}
+}